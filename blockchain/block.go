@@ -5,6 +5,8 @@ import (
 	"encoding/gob"
 	"log"
 	"time"
+
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
 )
 
 // Block is the structure of a block in a blockchain
@@ -17,13 +19,16 @@ type Block struct {
 	Height       int
 }
 
+// HashTransactions is the merkle root of the transactions, blocks have at
+// least their coinbase. ValidateBlock checks received blocks first
 func (b *Block) HashTransactions() []byte {
 	var txHashes [][]byte
 
 	for _, tx := range b.Transactions {
-		txHashes = append(txHashes, tx.Serialize())
+		txHashes = append(txHashes, tx.Encode())
 	}
-	txTree := NewMerkleTree(txHashes)
+	txTree, err := NewMerkleTree(txHashes)
+	Handle(err)
 
 	return txTree.Root.Data
 }
@@ -32,21 +37,44 @@ func CreateBlock(txns []*Transaction, prevHash []byte, height int) *Block {
 	block := new(Block)
 	block.Transactions = txns
 	block.PrevHash = prevHash
+	block.Timestamp = time.Now().Unix()
+	block.Height = height
 	pow := NewProof(block)
 	nonce, hash := pow.Run()
 	block.Nonce = nonce
 	block.Hash = hash
-	block.Timestamp = time.Now().Unix()
-	block.Height = height
 
 	return block
 }
 
-// Genesis creates the genesis block
+// Genesis mines a new genesis block, for custom networks
 func Genesis(coinBase *Transaction) *Block {
 	return CreateBlock([]*Transaction{coinBase}, []byte{}, 0)
 }
 
+// GenesisBlock rebuilds the genesis block of the active network from its
+// parameters
+func GenesisBlock() *Block {
+	params := chaincfg.Active
+
+	coinbase := coinbaseTx(params.GenesisCoinbaseData, TxOutput{params.Subsidy, params.GenesisLockingScript})
+	block := &Block{
+		Timestamp:    params.GenesisTimestamp,
+		Nonce:        params.GenesisNonce,
+		Transactions: []*Transaction{coinbase},
+		PrevHash:     []byte{},
+		Height:       0,
+	}
+
+	block.Hash = NewProof(block).Hash()
+
+	if !bytes.Equal(block.Hash, params.GenesisHash) {
+		log.Panicf("Genesis block of %s hashes to %x instead of %x", params.Name, block.Hash, params.GenesisHash)
+	}
+
+	return block
+}
+
 func (b *Block) Serialize() []byte {
 	var res bytes.Buffer
 	encoder := gob.NewEncoder(&res)
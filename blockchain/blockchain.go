@@ -2,36 +2,54 @@ package blockchain
 
 import (
 	"bytes"
-	"crypto/ecdsa"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
+	"os"
+	"path/filepath"
 	"runtime"
+	"sync"
 
 	"github.com/dgraph-io/badger"
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+	"gitlab.com/thesepehrm/first-blockchain/script"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
 )
 
 const (
-	dbPath = "./tmp/blocks_%s"
+	dbPath = "blocks_%s"
 )
 
+// chainPath is the database directory of the node on the active network
+func chainPath(nodeID string) string {
+	return filepath.Join(chaincfg.Active.DataDir, fmt.Sprintf(dbPath, nodeID))
+}
+
 // BlockChain is the structure for a blockchain
 type BlockChain struct {
 	LastHash []byte
 	Database *badger.DB
-}
+	SigCache *SigCache
 
-// InitBlockChain makes a new blockchain
-func InitBlockChain(address, nodeID string) *BlockChain {
-	var lastHash []byte
+	// assumedValid holds the blocks leading to the last checkpoint, see
+	// AssumeValidHeaders
+	assumedValid      map[string]bool
+	assumedValidMutex sync.Mutex
+}
 
-	path := fmt.Sprintf(dbPath, nodeID)
+// InitBlockChain makes a new blockchain starting with genesis
+func InitBlockChain(genesis *Block, nodeID string) *BlockChain {
+	path := chainPath(nodeID)
 
 	if DBExists(path) {
 		fmt.Println("Blockchain already exists.")
 		runtime.Goexit()
 	}
 
+	err := os.MkdirAll(chaincfg.Active.DataDir, 0755)
+	Handle(err)
+
 	opts := badger.DefaultOptions(path)
 	opts.Logger = nil
 
@@ -39,42 +57,31 @@ func InitBlockChain(address, nodeID string) *BlockChain {
 	Handle(err)
 
 	err = db.Update(func(txn *badger.Txn) error {
-		if _, err := txn.Get([]byte("lh")); err == badger.ErrKeyNotFound {
-			fmt.Println("No existing blockchains found. Creating a new blockchain...")
-			coinbaseTx := CoinbaseTx(address, "Genesis")
-			genesis := Genesis(coinbaseTx)
-			fmt.Println("Genesis Created!")
-			err = txn.Set(genesis.Hash, genesis.Serialize())
-			Handle(err)
-			err = txn.Set([]byte("lh"), genesis.Hash)
-
-			lastHash = genesis.Hash
-			return err
-		} else {
-			record, err := txn.Get([]byte("lh"))
-			Handle(err)
-			err = record.Value(func(val []byte) error {
-				lastHash = append([]byte{}, val...)
-				return nil
-			})
-			return err
-		}
+		err = txn.Set(genesis.Hash, genesis.Serialize())
+		Handle(err)
+		return txn.Set([]byte("lh"), genesis.Hash)
 	})
 
 	Handle(err)
 
-	blockchain := BlockChain{lastHash, db}
+	blockchain := BlockChain{LastHash: genesis.Hash, Database: db, SigCache: NewSigCache(DefaultSigCacheSize)}
+
+	UTXOSet := UTXOSet{&blockchain}
+	UTXOSet.ReIndex()
+
 	return &blockchain
 }
 
+// ContinueBlockChain opens the chain of the node, a new one starting with the
+// genesis block of the active network when the node has none
 func ContinueBlockChain(nodeID string) *BlockChain {
 	var lastHash []byte
 
-	path := fmt.Sprintf(dbPath, nodeID)
+	path := chainPath(nodeID)
 
 	if !DBExists(path) {
-		fmt.Println("No blockchain exists; Make one!")
-		runtime.Goexit()
+		fmt.Printf("No blockchain found, starting the %s chain\n", chaincfg.Active.Name)
+		return InitBlockChain(GenesisBlock(), nodeID)
 	}
 
 	opts := badger.DefaultOptions(path)
@@ -95,7 +102,13 @@ func ContinueBlockChain(nodeID string) *BlockChain {
 
 	Handle(err)
 
-	blockchain := BlockChain{lastHash, db}
+	blockchain := BlockChain{LastHash: lastHash, Database: db, SigCache: NewSigCache(DefaultSigCacheSize)}
+
+	if _, err := blockchain.GetBlock(chaincfg.Active.GenesisHash); err != nil {
+		db.Close()
+		log.Panicf("The blockchain in %s is not a %s chain", path, chaincfg.Active.Name)
+	}
+
 	return &blockchain
 }
 
@@ -154,6 +167,23 @@ func (chain *BlockChain) GetBlockHashes() [][]byte {
 	return hashes
 }
 
+// GetHeaders returns the headers of the chain, newest first
+func (chain *BlockChain) GetHeaders() []BlockHeader {
+	var headers []BlockHeader
+
+	iter := chain.Iterator()
+	for {
+		block := iter.Next()
+
+		headers = append(headers, block.Header())
+
+		if len(block.PrevHash) == 0 {
+			break
+		}
+	}
+	return headers
+}
+
 func (chain *BlockChain) GetBestHeight() int {
 	lastBlock := chain.getLastBlock()
 	return lastBlock.Height
@@ -239,7 +269,9 @@ func (chain *BlockChain) FindUTXO() map[string]TxOutputs {
 					}
 				}
 				outs := UTXO[txID]
-				outs.Outputs = append(outs.Outputs, out)
+				outs.Add(outIdx, out)
+				outs.Height = block.Height
+				outs.Timestamp = block.Timestamp
 				UTXO[txID] = outs
 			}
 			if !tx.IsCoinbase() {
@@ -258,7 +290,32 @@ func (chain *BlockChain) FindUTXO() map[string]TxOutputs {
 	return UTXO
 }
 
-func (chain *BlockChain) SignTransaction(tx *Transaction, privateKey ecdsa.PrivateKey) {
+// UsedPubKeyHashes returns the hex public key hashes that ever received an
+// output, used to discover the addresses of a deterministic wallet
+func (chain *BlockChain) UsedPubKeyHashes() map[string]bool {
+	used := make(map[string]bool)
+
+	iter := chain.Iterator()
+	for {
+		block := iter.Next()
+
+		for _, tx := range block.Transactions {
+			for _, out := range tx.Outputs {
+				if pubKeyHash, ok := script.ExtractPubKeyHash(out.LockingScript); ok {
+					used[hex.EncodeToString(pubKeyHash)] = true
+				}
+			}
+		}
+
+		if len(block.PrevHash) == 0 {
+			break
+		}
+	}
+
+	return used
+}
+
+func (chain *BlockChain) SignTransaction(tx *Transaction, privateKey wallet.PrivateKey) {
 	prevTxs := make(map[string]Transaction)
 
 	for _, in := range tx.Inputs {
@@ -266,8 +323,8 @@ func (chain *BlockChain) SignTransaction(tx *Transaction, privateKey ecdsa.Priva
 		Handle(err)
 		prevTxs[hex.EncodeToString(prevTx.ID)] = prevTx
 	}
-	tx.Sign(privateKey, prevTxs)
-
+	err := tx.Sign(privateKey, prevTxs, SigHashAll)
+	Handle(err)
 }
 
 func (chain *BlockChain) VerifyTransaction(tx *Transaction) bool {
@@ -275,12 +332,30 @@ func (chain *BlockChain) VerifyTransaction(tx *Transaction) bool {
 		return true
 	}
 
+	prevTxs, err := chain.prevTransactions(tx)
+	if err != nil {
+		return false
+	}
+
+	checks, ok := tx.scriptChecks(prevTxs)
+	if !ok {
+		return false
+	}
+
+	// Signatures found valid here are not verified again when mined
+	return verifyScripts(checks, chain.SigCache, false) == nil
+}
+
+// prevTransactions finds the transactions whose outputs tx spends
+func (chain *BlockChain) prevTransactions(tx *Transaction) (map[string]Transaction, error) {
 	prevTxs := make(map[string]Transaction)
 
 	for _, in := range tx.Inputs {
 		prevTx, err := chain.FindTransactions(in.ID)
-		Handle(err)
+		if err != nil {
+			return nil, err
+		}
 		prevTxs[hex.EncodeToString(prevTx.ID)] = prevTx
 	}
-	return tx.Verify(prevTxs)
+	return prevTxs, nil
 }
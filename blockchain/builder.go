@@ -0,0 +1,152 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"gitlab.com/thesepehrm/first-blockchain/script"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+// TxBuilder assembles a transaction paying to any number of outputs out of
+// the unspent outputs of several addresses
+type TxBuilder struct {
+	UTXO     *UTXOSet
+	From     []string // Addresses whose outputs may be spent
+	Change   string   // Receives what is left, defaults to the first address in From
+	LockTime int64
+	Selector CoinSelector    // Picks the outputs to spend, DefaultCoinSelection when nil
+	Selected []UnspentOutput // Outputs spent by the last Build
+	Exclude  map[string]bool // TXID:INDEX outpoints that must not be spent
+	outputs  []TxOutput
+	err      error // First invalid output, returned by Build
+}
+
+func NewTxBuilder(UTXO *UTXOSet, from []string) *TxBuilder {
+	return &TxBuilder{UTXO: UTXO, From: from}
+}
+
+func (b *TxBuilder) AddOutput(to string, amount int) *TxBuilder {
+	out, err := NewTxOutput(amount, to)
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+		return b
+	}
+
+	b.outputs = append(b.outputs, *out)
+	return b
+}
+
+// Build selects inputs covering the outputs and returns the unsigned
+// transaction
+func (b *TxBuilder) Build() (*Transaction, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.From) == 0 {
+		return nil, errors.New("no source addresses")
+	}
+	if len(b.outputs) == 0 {
+		return nil, errors.New("no outputs")
+	}
+
+	amount := 0
+	for _, out := range b.outputs {
+		if out.Value <= 0 {
+			return nil, fmt.Errorf("invalid amount %d", out.Value)
+		}
+		amount += out.Value
+	}
+
+	var lockHashes [][]byte
+	for _, address := range b.From {
+		_, lockHash, err := wallet.DecodeAddress(address)
+		if err != nil {
+			return nil, err
+		}
+		lockHashes = append(lockHashes, lockHash)
+	}
+
+	selector := b.Selector
+	if selector == nil {
+		selector, _ = NewCoinSelector(DefaultCoinSelection)
+	}
+
+	var candidates []UnspentOutput
+	for _, unspent := range b.UTXO.FindUnspentOutputs(lockHashes) {
+		if !b.Exclude[wallet.Outpoint(unspent.TxID, unspent.Index)] {
+			candidates = append(candidates, unspent)
+		}
+	}
+
+	selected, err := selector.Select(candidates, amount)
+	if err != nil {
+		return nil, err
+	}
+	b.Selected = selected
+
+	var inputs []TxInput
+	accumulated := 0
+	for _, unspent := range selected {
+		accumulated += unspent.Output.Value
+		inputs = append(inputs, TxInput{unspent.TxID, unspent.Index, nil, sequenceForLockTime(b.LockTime)})
+	}
+
+	outputs := append([]TxOutput{}, b.outputs...)
+	if accumulated > amount {
+		change := b.Change
+		if change == "" {
+			change = b.From[0]
+		}
+		out, err := NewTxOutput(accumulated-amount, change)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, *out)
+	}
+
+	tx := Transaction{nil, inputs, outputs, b.LockTime}
+	tx.ID = tx.Hash()
+
+	return &tx, nil
+}
+
+// SignTransactionWithWallets signs every input with the key of the wallet
+// owning the output it spends
+func (chain *BlockChain) SignTransactionWithWallets(tx *Transaction, wallets *wallet.Wallets) error {
+	prevTxs := make(map[string]Transaction)
+	keys := make(map[string]wallet.PrivateKey)
+
+	for inId, in := range tx.Inputs {
+		prevTx, err := chain.FindTransactions(in.ID)
+		if err != nil {
+			return err
+		}
+		prevTxs[hex.EncodeToString(prevTx.ID)] = prevTx
+
+		pubKeyHash, ok := script.ExtractPubKeyHash(prevTx.Outputs[in.Out].LockingScript)
+		if !ok {
+			return fmt.Errorf("input %d does not spend a pay-to-pubkey-hash output", inId)
+		}
+
+		w, ok := wallets.FindByPublicKeyHash(pubKeyHash)
+		if !ok {
+			if watchOnly, ok := wallets.FindWatchOnlyByPublicKeyHash(pubKeyHash); ok {
+				return fmt.Errorf("input %d spends %s: %w", inId, watchOnly.Address, wallet.ErrWatchOnly)
+			}
+			return fmt.Errorf("no key for input %d", inId)
+		}
+		keys[string(w.Address())] = w.PrivateKey
+	}
+
+	for _, privateKey := range keys {
+		if err := tx.Sign(privateKey, prevTxs, SigHashAll); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
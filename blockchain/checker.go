@@ -0,0 +1,83 @@
+package blockchain
+
+import (
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+// txChecker lets the script interpreter check signatures and lock times of
+// one input of a transaction
+type txChecker struct {
+	tx      *Transaction
+	inId    int
+	amount  int        // Value of the output spent by the input
+	hashes  *SigHashes // Shared by the checkers of all inputs of tx
+	cache   *SigCache  // Signatures already found valid, may be nil
+	inBlock bool       // Cache hits are final, the transaction is being mined
+}
+
+func (c *txChecker) CheckSig(signature, pubKey, subScript []byte) bool {
+	signature, hashType, ok := splitSignature(signature)
+	if !ok {
+		return false
+	}
+
+	hash, err := c.tx.SignatureHash(c.inId, subScript, c.amount, hashType, c.hashes)
+	if err != nil {
+		return false
+	}
+
+	// Only well formed signatures and keys are looked up, the cache must not
+	// vouch for encodings verification would refuse
+	if !wallet.CheckSignatureEncoding(pubKey, signature) {
+		return false
+	}
+
+	if c.cache != nil && c.cache.Exists(hash, signature, pubKey, c.inBlock) {
+		return true
+	}
+
+	// The type tags of the key and signature pick the signature scheme
+	if !wallet.VerifySignature(pubKey, hash, signature) {
+		return false
+	}
+
+	if c.cache != nil && !c.inBlock {
+		c.cache.Add(hash, signature, pubKey)
+	}
+	return true
+}
+
+// CheckLockTime follows OP_CHECKLOCKTIMEVERIFY: the transaction lock time
+// must be of the same kind as the required one, at least as late, and enforced
+func (c *txChecker) CheckLockTime(lockTime int64) bool {
+	txLockTime := c.tx.LockTime
+
+	if (lockTime < LockTimeThreshold) != (txLockTime < LockTimeThreshold) {
+		return false
+	}
+	if lockTime > txLockTime {
+		return false
+	}
+
+	return c.tx.Inputs[c.inId].Sequence != MaxSequence
+}
+
+// CheckSequence follows OP_CHECKSEQUENCEVERIFY: the input must carry a
+// relative lock of the same kind that is at least as long as the required one
+func (c *txChecker) CheckSequence(sequence int64) bool {
+	required := uint32(sequence)
+	if required&SequenceLockTimeDisableFlag != 0 {
+		return true
+	}
+
+	actual := c.tx.Inputs[c.inId].Sequence
+	if actual&SequenceLockTimeDisableFlag != 0 {
+		return false
+	}
+
+	if required&SequenceLockTimeTypeFlag != actual&SequenceLockTimeTypeFlag {
+		return false
+	}
+
+	return required&SequenceLockTimeMask <= actual&SequenceLockTimeMask
+}
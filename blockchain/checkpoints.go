@@ -0,0 +1,105 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+)
+
+// SkipCheckpointedScripts lets a node accept the ancestors of the last
+// checkpoint without verifying their scripts, the checkpoint vouches for
+// them. Only blocks proven to lead to it by AssumeValidHeaders are skipped,
+// a network without checkpoints past the genesis verifies every block
+var SkipCheckpointedScripts = true
+
+// checkCheckpoints rejects blocks conflicting with the checkpoints of the
+// active network, checkHeader made sure the height of block follows its
+// parent
+func (chain *BlockChain) checkCheckpoints(block *Block) error {
+	params := chaincfg.Active
+	last := params.LastCheckpoint()
+
+	if hash, ok := params.CheckpointHash(block.Height); ok && !bytes.Equal(hash, block.Hash) {
+		return fmt.Errorf("block at height %d conflicts with checkpoint %x", block.Height, hash)
+	}
+
+	// Once the chain went past the last checkpoint, a block below it that is
+	// not ours starts a fork of the checkpointed chain
+	if last != nil && block.Height <= last.Height && chain.GetBestHeight() >= last.Height {
+		if _, err := chain.GetBlock(block.Hash); err != nil {
+			return fmt.Errorf("block at height %d forks the chain below checkpoint %d", block.Height, last.Height)
+		}
+	}
+
+	return nil
+}
+
+// SyncingToCheckpoint reports whether the node is below the last checkpoint
+// and would skip the scripts of its ancestors, the headers of a peer are
+// worth fetching then
+func (chain *BlockChain) SyncingToCheckpoint() bool {
+	last := chaincfg.Active.LastCheckpoint()
+
+	return SkipCheckpointedScripts && last != nil && last.Height > 0 &&
+		chain.GetBestHeight() < last.Height
+}
+
+// AssumeValidHeaders checks headers, the chain of a peer oldest first from
+// the genesis, and when they lead to the last checkpoint remembers the blocks
+// up to it as its ancestors
+func (chain *BlockChain) AssumeValidHeaders(headers []BlockHeader) error {
+	params := chaincfg.Active
+	last := params.LastCheckpoint()
+	if last == nil || len(headers) <= last.Height {
+		return fmt.Errorf("headers do not reach the last checkpoint")
+	}
+
+	var prevHash []byte
+	hashes := make([]string, 0, last.Height+1)
+
+	for height, header := range headers[:last.Height+1] {
+		hash := header.Hash()
+
+		switch {
+		case header.Height != height || !bytes.Equal(header.PrevHash, prevHash):
+			return fmt.Errorf("header at height %d does not follow its parent", height)
+		case height == 0 && !bytes.Equal(hash, params.GenesisHash):
+			return fmt.Errorf("headers start from another genesis block %x", hash)
+		case height > 0 && !header.Validate():
+			return fmt.Errorf("header at height %d misses the proof of work target", height)
+		}
+
+		prevHash = hash
+		hashes = append(hashes, hex.EncodeToString(hash))
+	}
+
+	if !bytes.Equal(prevHash, last.Hash) {
+		return fmt.Errorf("headers conflict with checkpoint %x", last.Hash)
+	}
+
+	chain.assumedValidMutex.Lock()
+	defer chain.assumedValidMutex.Unlock()
+
+	chain.assumedValid = make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		chain.assumedValid[hash] = true
+	}
+
+	return nil
+}
+
+// assumeValid reports whether the scripts of block are skipped, block is an
+// ancestor of the last checkpoint. ValidateBlock checked that its hash covers
+// its content
+func (chain *BlockChain) assumeValid(block *Block) bool {
+	if !SkipCheckpointedScripts {
+		return false
+	}
+
+	chain.assumedValidMutex.Lock()
+	defer chain.assumedValidMutex.Unlock()
+
+	return chain.assumedValid[hex.EncodeToString(block.Hash)]
+}
@@ -0,0 +1,88 @@
+package blockchain
+
+import (
+	"testing"
+
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+// checkpointedChain mines blocks on top of the regtest genesis and makes the
+// last of them the checkpoint of the active network
+func checkpointedChain(t *testing.T, blocks int) []*Block {
+	params := chaincfg.RegTestParams
+	active := chaincfg.Active
+	chaincfg.Active = &params
+	t.Cleanup(func() { chaincfg.Active = active })
+
+	chain := []*Block{GenesisBlock()}
+	for height := 1; height <= blocks; height++ {
+		coinbase := coinbaseTx("test", TxOutput{params.Subsidy, nil})
+		chain = append(chain, CreateBlock([]*Transaction{coinbase}, chain[height-1].Hash, height))
+	}
+
+	last := chain[len(chain)-1]
+	params.Checkpoints = []chaincfg.Checkpoint{{Height: last.Height, Hash: last.Hash}}
+	return chain
+}
+
+func headersOf(blocks []*Block) []BlockHeader {
+	var headers []BlockHeader
+	for _, block := range blocks {
+		headers = append(headers, block.Header())
+	}
+	return headers
+}
+
+func TestAssumeValidHeaders(t *testing.T) {
+	blocks := checkpointedChain(t, 3)
+	chain := &BlockChain{}
+
+	if err := chain.AssumeValidHeaders(headersOf(blocks)); err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range blocks {
+		if !chain.assumeValid(block) {
+			t.Errorf("block at height %d is not assumed valid", block.Height)
+		}
+	}
+
+	fork := CreateBlock([]*Transaction{coinbaseTx("fork", TxOutput{10, nil})}, blocks[1].Hash, 2)
+	if chain.assumeValid(fork) {
+		t.Error("a block off the checkpointed chain is assumed valid")
+	}
+
+	headers := headersOf(blocks)
+	headers[1].Nonce++
+	if err := (&BlockChain{}).AssumeValidHeaders(headers); err == nil {
+		t.Error("headers with a tampered nonce were accepted")
+	}
+	if err := (&BlockChain{}).AssumeValidHeaders(headersOf(append(blocks[:2:2], fork))); err == nil {
+		t.Error("headers conflicting with the checkpoint were accepted")
+	}
+}
+
+func TestValidateBlockHash(t *testing.T) {
+	blocks := checkpointedChain(t, 1)
+
+	block := *blocks[1]
+	block.Timestamp++
+	if err := (&BlockChain{}).ValidateBlock(&block); err == nil {
+		t.Error("a block not matching its hash was accepted")
+	}
+
+	// Rejected before anything hashes the transactions
+	empty := *blocks[1]
+	empty.Transactions = nil
+	if err := (&BlockChain{}).ValidateBlock(&empty); err == nil {
+		t.Error("a block without transactions was accepted")
+	}
+
+	privateKey, _ := wallet.NewKeyPair(wallet.KeyP256)
+	tx, _ := spendingTx(privateKey, 1, 1)
+	noCoinbase := *blocks[1]
+	noCoinbase.Transactions = []*Transaction{tx, blocks[1].Transactions[0]}
+	if err := (&BlockChain{}).ValidateBlock(&noCoinbase); err == nil {
+		t.Error("a block not starting with its coinbase was accepted")
+	}
+}
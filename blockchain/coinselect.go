@@ -0,0 +1,146 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// CoinSelector picks which unspent outputs fund a transaction
+type CoinSelector interface {
+	// Select returns outputs out of candidates worth at least target
+	Select(candidates []UnspentOutput, target int) ([]UnspentOutput, error)
+}
+
+// Names of the available coin selection strategies
+const (
+	LargestFirstSelection   = "largest"
+	SmallestFirstSelection  = "smallest"
+	BranchAndBoundSelection = "bnb"
+	RandomSelection         = "random"
+)
+
+// DefaultCoinSelection is used when neither the wallet nor the transaction
+// asks for a strategy
+const DefaultCoinSelection = LargestFirstSelection
+
+func NewCoinSelector(name string) (CoinSelector, error) {
+	switch name {
+	case "", DefaultCoinSelection:
+		return LargestFirst{}, nil
+	case SmallestFirstSelection:
+		return SmallestFirst{}, nil
+	case BranchAndBoundSelection:
+		return BranchAndBound{Fallback: LargestFirst{}}, nil
+	case RandomSelection:
+		return Random{}, nil
+	}
+	return nil, fmt.Errorf("unknown coin selection strategy %q", name)
+}
+
+// LargestFirst spends the biggest outputs first, keeping the number of
+// inputs low
+type LargestFirst struct{}
+
+func (LargestFirst) Select(candidates []UnspentOutput, target int) ([]UnspentOutput, error) {
+	sorted := append([]UnspentOutput{}, candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Output.Value > sorted[j].Output.Value })
+	return accumulate(sorted, target)
+}
+
+// SmallestFirst spends the smallest outputs first, consolidating dust
+type SmallestFirst struct{}
+
+func (SmallestFirst) Select(candidates []UnspentOutput, target int) ([]UnspentOutput, error) {
+	sorted := append([]UnspentOutput{}, candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Output.Value < sorted[j].Output.Value })
+	return accumulate(sorted, target)
+}
+
+// Random spends outputs in random order, so that the selection does not
+// reveal which outputs belong together
+type Random struct{}
+
+func (Random) Select(candidates []UnspentOutput, target int) ([]UnspentOutput, error) {
+	shuffled := append([]UnspentOutput{}, candidates...)
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	random.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	return accumulate(shuffled, target)
+}
+
+// maxBranchAndBoundTries bounds the depth first search of BranchAndBound
+const maxBranchAndBoundTries = 100000
+
+// BranchAndBound searches for a set of outputs worth exactly the target so
+// that the transaction needs no change output, and leaves it to Fallback
+// when there is none
+type BranchAndBound struct {
+	Fallback CoinSelector
+}
+
+func (b BranchAndBound) Select(candidates []UnspentOutput, target int) ([]UnspentOutput, error) {
+	sorted := append([]UnspentOutput{}, candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Output.Value > sorted[j].Output.Value })
+
+	// remaining[i] is the value of sorted[i:], used to cut hopeless branches
+	remaining := make([]int, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + sorted[i].Output.Value
+	}
+
+	var selected []int
+	tries := 0
+
+	var search func(i, sum int) bool
+	search = func(i, sum int) bool {
+		tries++
+		switch {
+		case sum == target:
+			return true
+		case sum > target, i == len(sorted), sum+remaining[i] < target, tries > maxBranchAndBoundTries:
+			return false
+		}
+
+		selected = append(selected, i)
+		if search(i+1, sum+sorted[i].Output.Value) {
+			return true
+		}
+		selected = selected[:len(selected)-1]
+
+		return search(i+1, sum)
+	}
+
+	if search(0, 0) {
+		var result []UnspentOutput
+		for _, i := range selected {
+			result = append(result, sorted[i])
+		}
+		return result, nil
+	}
+
+	if b.Fallback == nil {
+		return nil, fmt.Errorf("no combination of outputs is worth exactly %d", target)
+	}
+	return b.Fallback.Select(candidates, target)
+}
+
+// accumulate takes outputs in the given order until they cover the target
+func accumulate(ordered []UnspentOutput, target int) ([]UnspentOutput, error) {
+	var selected []UnspentOutput
+	accumulated := 0
+
+	for _, unspent := range ordered {
+		if accumulated >= target {
+			break
+		}
+		accumulated += unspent.Output.Value
+		selected = append(selected, unspent)
+	}
+
+	if accumulated < target {
+		return nil, fmt.Errorf("not enough funds: have %d, need %d", accumulated, target)
+	}
+
+	return selected, nil
+}
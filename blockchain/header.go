@@ -0,0 +1,53 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/big"
+
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+)
+
+// BlockHeader is the part of a block its proof of work commits to, the
+// transactions only through their merkle root. Headers let a node check the
+// chain of a peer before downloading its blocks
+type BlockHeader struct {
+	PrevHash   []byte
+	MerkleRoot []byte
+	Timestamp  int64
+	Height     int
+	Nonce      int
+}
+
+func (b *Block) Header() BlockHeader {
+	return BlockHeader{b.PrevHash, b.HashTransactions(), b.Timestamp, b.Height, b.Nonce}
+}
+
+func (h BlockHeader) data() []byte {
+	return bytes.Join(
+		[][]byte{
+			h.PrevHash,
+			h.MerkleRoot,
+			ToHex(h.Timestamp),
+			ToHex(int64(h.Height)),
+			ToHex(int64(h.Nonce)),
+			ToHex(int64(chaincfg.Active.Difficulty)),
+		},
+		[]byte{},
+	)
+}
+
+// Hash is the hash of the block the header belongs to
+func (h BlockHeader) Hash() []byte {
+	hash := sha256.Sum256(h.data())
+	return hash[:]
+}
+
+// Validate reports whether the hash of the header meets the target of the
+// active network
+func (h BlockHeader) Validate() bool {
+	var intHash big.Int
+
+	intHash.SetBytes(h.Hash())
+	return intHash.Cmp(proofTarget()) == -1
+}
@@ -0,0 +1,126 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+const (
+	// LockTimeThreshold separates the two meanings of a lock time: values
+	// below it are block heights, values at or above it are unix timestamps
+	LockTimeThreshold = 500000000
+
+	// MaxSequence marks an input as final, an input with this sequence
+	// number does not take part in lock time enforcement
+	MaxSequence = uint32(0xffffffff)
+)
+
+// IsFinal reports whether the transaction can be included in a block at the
+// given height and time
+func (tx *Transaction) IsFinal(blockHeight int, blockTime int64) bool {
+	if tx.LockTime == 0 {
+		return true
+	}
+
+	limit := int64(blockHeight)
+	if tx.LockTime >= LockTimeThreshold {
+		limit = blockTime
+	}
+
+	if tx.LockTime < limit {
+		return true
+	}
+
+	// A lock time is ignored when every input has opted out of it
+	for _, in := range tx.Inputs {
+		if in.Sequence != MaxSequence {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sequenceForLockTime returns the sequence number new inputs should carry so
+// that the given lock time is actually enforced
+func sequenceForLockTime(lockTime int64) uint32 {
+	if lockTime == 0 {
+		return MaxSequence
+	}
+	return MaxSequence - 1
+}
+
+const (
+	// SequenceLockTimeDisableFlag turns off the relative lock of an input
+	SequenceLockTimeDisableFlag = uint32(1 << 31)
+
+	// SequenceLockTimeTypeFlag makes the relative lock count seconds
+	// instead of blocks
+	SequenceLockTimeTypeFlag = uint32(1 << 22)
+
+	// SequenceLockTimeMask extracts the relative lock value from a sequence
+	SequenceLockTimeMask = uint32(0x0000ffff)
+
+	// SequenceLockTimeGranularity is the log2 of the seconds counted by one
+	// unit of a time based relative lock, 2^9 = 512 seconds
+	SequenceLockTimeGranularity = 9
+)
+
+// RelativeLockBlocks returns the sequence number of an input that can only be
+// spent once the output it references has n confirmations
+func RelativeLockBlocks(n uint16) uint32 {
+	return uint32(n)
+}
+
+// RelativeLockSeconds returns the sequence number of an input that can only
+// be spent the given number of seconds after the output it references was
+// confirmed, rounded up to the 512 seconds granularity
+func RelativeLockSeconds(seconds uint32) uint32 {
+	units := (seconds + (1 << SequenceLockTimeGranularity) - 1) >> SequenceLockTimeGranularity
+	if units > SequenceLockTimeMask {
+		units = SequenceLockTimeMask
+	}
+	return SequenceLockTimeTypeFlag | units
+}
+
+// HasRelativeLock reports whether the input is subject to a relative lock
+func (in *TxInput) HasRelativeLock() bool {
+	return in.Sequence&SequenceLockTimeDisableFlag == 0
+}
+
+// CheckSequenceLocks verifies the relative locks of the inputs against a
+// block at the given height and time. prevOutputs maps the hex id of every
+// transaction referenced by a locked input to its unspent outputs, which
+// carry the height and time they were confirmed at
+func (tx *Transaction) CheckSequenceLocks(blockHeight int, blockTime int64, prevOutputs map[string]TxOutputs) error {
+	if tx.IsCoinbase() {
+		return nil
+	}
+
+	for inId, in := range tx.Inputs {
+		if !in.HasRelativeLock() {
+			continue
+		}
+
+		prev, ok := prevOutputs[hex.EncodeToString(in.ID)]
+		if !ok {
+			return fmt.Errorf("input %d references an unknown output", inId)
+		}
+
+		value := in.Sequence & SequenceLockTimeMask
+
+		if in.Sequence&SequenceLockTimeTypeFlag != 0 {
+			minTime := prev.Timestamp + int64(value)<<SequenceLockTimeGranularity
+			if blockTime < minTime {
+				return fmt.Errorf("input %d is locked until %d", inId, minTime)
+			}
+		} else {
+			minHeight := prev.Height + int(value)
+			if blockHeight < minHeight {
+				return fmt.Errorf("input %d is locked until height %d", inId, minHeight)
+			}
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package blockchain
 
 import (
 	"crypto/sha256"
+	"errors"
 	"log"
 )
 
@@ -36,7 +37,12 @@ func NewMerkleNode(left *MerkleNode, right *MerkleNode, data []byte) *MerkleNode
 	return node
 }
 
-func NewMerkleTree(data [][]byte) *MerkleTree {
+// NewMerkleTree builds the tree of data, a tree has at least one leaf
+func NewMerkleTree(data [][]byte) (*MerkleTree, error) {
+	if len(data) == 0 {
+		return nil, errors.New("merkle tree without leaves")
+	}
+
 	merkeTree := new(MerkleTree)
 
 	var merkleRow []*MerkleNode
@@ -61,5 +67,5 @@ func NewMerkleTree(data [][]byte) *MerkleTree {
 		merkleRow = tempRow
 	}
 	merkeTree.Root = merkleRow[0]
-	return merkeTree
+	return merkeTree, nil
 }
@@ -0,0 +1,25 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestNewMerkleTree(t *testing.T) {
+	if _, err := NewMerkleTree(nil); err == nil {
+		t.Error("NewMerkleTree built a tree without leaves")
+	}
+
+	// An odd leaf is paired with itself
+	leaf := sha256.Sum256([]byte("leaf"))
+	root := sha256.Sum256(append(leaf[:], leaf[:]...))
+
+	tree, err := NewMerkleTree([][]byte{[]byte("leaf")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tree.Root.Data, root[:]) {
+		t.Errorf("root = %x, want %x", tree.Root.Data, root)
+	}
+}
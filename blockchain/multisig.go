@@ -0,0 +1,164 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+	"gitlab.com/thesepehrm/first-blockchain/script"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+// NewMultiSigTransaction creates an unsigned transaction spending from the
+// multisig address from, co-signers add their signatures with SignMultiSig
+func NewMultiSigTransaction(from, to string, amount int, lockTime int64, UTXO *UTXOSet) *Transaction {
+	if version, _, err := wallet.DecodeAddress(from); err != nil || version != chaincfg.Active.ScriptHashAddrID {
+		log.Panic("Error: not a multisig address")
+	}
+
+	return NewUnsignedTransaction(from, to, amount, lockTime, UTXO)
+}
+
+func (chain *BlockChain) SignMultiSigTransaction(tx *Transaction, privateKey wallet.PrivateKey, redeemScript []byte) {
+	prevTxs := make(map[string]Transaction)
+
+	for _, in := range tx.Inputs {
+		prevTx, err := chain.FindTransactions(in.ID)
+		Handle(err)
+		prevTxs[hex.EncodeToString(prevTx.ID)] = prevTx
+	}
+	err := tx.SignMultiSig(privateKey, redeemScript, prevTxs, SigHashAll)
+	Handle(err)
+}
+
+// SignMultiSig adds a signature with privateKey to every input spending a
+// pay-to-script-hash output guarded by redeemScript, keeping the signatures
+// collected so far
+func (tx *Transaction) SignMultiSig(privateKey wallet.PrivateKey, redeemScript []byte, prevTxs map[string]Transaction, hashType SigHashType) error {
+	if tx.IsCoinbase() {
+		return nil
+	}
+
+	scriptHash := script.Hash160(redeemScript)
+	hashes := NewSigHashes(tx, prevTxs)
+
+	for inId, in := range tx.Inputs {
+		prevTx, ok := prevTxs[hex.EncodeToString(in.ID)]
+		if !ok {
+			log.Panic("Error: Previous transaction does not exist")
+		}
+
+		prevOut := prevTx.Outputs[in.Out]
+		lockHash, ok := script.ExtractScriptHash(prevOut.LockingScript)
+		if !ok || !bytes.Equal(lockHash, scriptHash) {
+			continue
+		}
+
+		hash, err := tx.SignatureHash(inId, redeemScript, prevOut.Value, hashType, hashes)
+		if err != nil {
+			return err
+		}
+
+		signature, err := privateKey.Sign(hash)
+		if err != nil {
+			return err
+		}
+		signature = append(signature, byte(hashType))
+
+		signatures, _, _ := multiSigSignatures(in.UnlockingScript)
+		signatures = append(signatures, signature)
+
+		tx.Inputs[inId].UnlockingScript = tx.mergeMultiSig(inId, redeemScript, prevOut.Value, signatures, hashes)
+	}
+
+	return nil
+}
+
+// CombineSignatures merges the multisig signatures other collected for the
+// same transaction into tx, the chain provides the values signatures commit to
+func (chain *BlockChain) CombineSignatures(tx, other *Transaction) error {
+	prevTxs, err := chain.prevTransactions(tx)
+	if err != nil {
+		return err
+	}
+	return tx.CombineSignatures(other, prevTxs)
+}
+
+// CombineSignatures merges the multisig signatures other collected for the
+// same transaction into tx
+func (tx *Transaction) CombineSignatures(other *Transaction, prevTxs map[string]Transaction) error {
+	if !bytes.Equal(tx.ID, other.ID) || len(tx.Inputs) != len(other.Inputs) {
+		return errors.New("signatures belong to a different transaction")
+	}
+
+	hashes := NewSigHashes(tx, prevTxs)
+	for inId, in := range tx.Inputs {
+		prevTx := prevTxs[hex.EncodeToString(in.ID)]
+		if in.Out < 0 || in.Out >= len(prevTx.Outputs) {
+			return fmt.Errorf("input %d spends an unknown output", inId)
+		}
+
+		signatures, redeemScript, ok := multiSigSignatures(tx.Inputs[inId].UnlockingScript)
+		otherSignatures, otherRedeemScript, otherOk := multiSigSignatures(other.Inputs[inId].UnlockingScript)
+
+		switch {
+		case !otherOk:
+			continue
+		case !ok:
+			redeemScript = otherRedeemScript
+		case !bytes.Equal(redeemScript, otherRedeemScript):
+			return errors.New("signatures are for a different multisig account")
+		}
+
+		signatures = append(signatures, otherSignatures...)
+		tx.Inputs[inId].UnlockingScript = tx.mergeMultiSig(inId, redeemScript, prevTx.Outputs[in.Out].Value, signatures, hashes)
+	}
+
+	return nil
+}
+
+// mergeMultiSig builds the unlocking script of a multisig input out of the
+// valid signatures found in the pool, ordered like the public keys they
+// belong to and capped at the required count
+func (tx *Transaction) mergeMultiSig(inId int, redeemScript []byte, amount int, pool [][]byte, hashes *SigHashes) []byte {
+	required, pubKeys, ok := script.ExtractMultiSig(redeemScript)
+	if !ok {
+		log.Panic("Error: not a multisig redeem script")
+	}
+
+	checker := &txChecker{tx: tx, inId: inId, amount: amount, hashes: hashes}
+
+	var signatures [][]byte
+	for _, pubKey := range pubKeys {
+		if len(signatures) == required {
+			break
+		}
+		for _, signature := range pool {
+			if checker.CheckSig(signature, pubKey, redeemScript) {
+				signatures = append(signatures, signature)
+				break
+			}
+		}
+	}
+
+	return script.MultiSigUnlock(signatures, redeemScript)
+}
+
+// multiSigSignatures splits the unlocking script of a multisig input into its
+// signatures and redeem script
+func multiSigSignatures(unlockingScript []byte) ([][]byte, []byte, bool) {
+	pushes, ok := script.ExtractPushes(unlockingScript)
+	if !ok || len(pushes) < 2 || len(pushes[0]) != 0 {
+		return nil, nil, false
+	}
+
+	redeemScript := pushes[len(pushes)-1]
+	if _, _, ok := script.ExtractMultiSig(redeemScript); !ok {
+		return nil, nil, false
+	}
+
+	return pushes[1 : len(pushes)-1], redeemScript, true
+}
@@ -8,9 +8,9 @@ import (
 	"log"
 	"math"
 	"math/big"
-)
 
-const Difficulty = 20 // Fact: Initial difficulty of Bitcoin was 20
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+)
 
 type ProofOfWork struct {
 	Block  *Block
@@ -18,24 +18,21 @@ type ProofOfWork struct {
 }
 
 func NewProof(b *Block) *ProofOfWork {
-	target := big.NewInt(1)
-	target.Lsh(target, uint(256-Difficulty))
-	pow := &ProofOfWork{b, target}
+	pow := &ProofOfWork{b, proofTarget()}
 	return pow
 }
 
-func (pow *ProofOfWork) InitData(nonce int) []byte {
-	data := bytes.Join(
-		[][]byte{
-			pow.Block.PrevHash,
-			pow.Block.HashTransactions(),
-			ToHex(int64(nonce)),
-			ToHex(int64(Difficulty)),
-		},
-		[]byte{},
-	)
+// proofTarget is the value block hashes of the active network stay below
+func proofTarget() *big.Int {
+	target := big.NewInt(1)
+	target.Lsh(target, uint(256-chaincfg.Active.Difficulty))
+	return target
+}
 
-	return data
+func (pow *ProofOfWork) InitData(nonce int) []byte {
+	header := pow.Block.Header()
+	header.Nonce = nonce
+	return header.data()
 }
 
 func (pow *ProofOfWork) Run() (int, []byte) {
@@ -57,13 +54,16 @@ func (pow *ProofOfWork) Run() (int, []byte) {
 	return nonce, hash[:]
 }
 
+// Hash recomputes the hash of the block from its content
+func (pow *ProofOfWork) Hash() []byte {
+	hash := sha256.Sum256(pow.InitData(pow.Block.Nonce))
+	return hash[:]
+}
+
 func (pow *ProofOfWork) Validate() bool {
 	var intHash big.Int
 
-	data := pow.InitData(pow.Block.Nonce)
-	hash := sha256.Sum256(data)
-
-	intHash.SetBytes(hash[:])
+	intHash.SetBytes(pow.Hash())
 	return intHash.Cmp(pow.Target) == -1
 }
 
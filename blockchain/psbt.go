@@ -0,0 +1,328 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"gitlab.com/thesepehrm/first-blockchain/script"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+// PartialTransaction carries an unsigned transaction together with everything
+// a signer needs, so that it can be signed on a machine without the chain
+type PartialTransaction struct {
+	Tx     Transaction
+	Inputs []PartialInput
+}
+
+// PartialInput describes the output spent by one input of the transaction
+// and the signatures collected for it so far. Signatures commit to the values
+// of the PrevOutput of every input, they are invalid when one was misreported
+type PartialInput struct {
+	PrevOutput   TxOutput
+	RedeemScript []byte            // Set when PrevOutput pays to a script hash
+	Hints        []KeyHint         // Keys expected to sign the input
+	Signatures   map[string][]byte // Hex public key -> signature
+}
+
+// KeyHint tells a signer which of its keys is needed
+type KeyHint struct {
+	PublicKey []byte
+	Path      string // Derivation path for deterministic wallets, if known
+}
+
+// NewPartialTransaction wraps an unsigned transaction, looking up the outputs
+// spent by its inputs
+func (chain *BlockChain) NewPartialTransaction(tx *Transaction) (*PartialTransaction, error) {
+	ptx := PartialTransaction{Tx: *tx}
+
+	for _, in := range tx.Inputs {
+		prevTx, err := chain.FindTransactions(in.ID)
+		if err != nil {
+			return nil, fmt.Errorf("previous transaction %x: %s", in.ID, err)
+		}
+		if in.Out < 0 || in.Out >= len(prevTx.Outputs) {
+			return nil, fmt.Errorf("previous transaction %x has no output %d", in.ID, in.Out)
+		}
+
+		ptx.Inputs = append(ptx.Inputs, PartialInput{
+			PrevOutput: prevTx.Outputs[in.Out],
+			Signatures: make(map[string][]byte),
+		})
+	}
+
+	return &ptx, nil
+}
+
+// AddRedeemScript attaches redeemScript to the inputs spending outputs locked
+// to its hash, and records its multisig keys as hints
+func (ptx *PartialTransaction) AddRedeemScript(redeemScript []byte) {
+	scriptHash := script.Hash160(redeemScript)
+
+	for i := range ptx.Inputs {
+		in := &ptx.Inputs[i]
+		lockHash, ok := script.ExtractScriptHash(in.PrevOutput.LockingScript)
+		if !ok || !bytes.Equal(lockHash, scriptHash) {
+			continue
+		}
+
+		in.RedeemScript = redeemScript
+		if _, pubKeys, ok := script.ExtractMultiSig(redeemScript); ok {
+			for _, pubKey := range pubKeys {
+				in.AddHint(KeyHint{PublicKey: pubKey})
+			}
+		}
+	}
+}
+
+// AddKeyHint records the key as a hint on every input it can sign
+func (ptx *PartialTransaction) AddKeyHint(hint KeyHint) {
+	for i := range ptx.Inputs {
+		if ptx.Inputs[i].canSign(hint.PublicKey) {
+			ptx.Inputs[i].AddHint(hint)
+		}
+	}
+}
+
+// AddHint records a key expected to sign the input
+func (in *PartialInput) AddHint(hint KeyHint) {
+	for _, known := range in.Hints {
+		if bytes.Equal(known.PublicKey, hint.PublicKey) {
+			return
+		}
+	}
+	in.Hints = append(in.Hints, hint)
+}
+
+// subScript returns the script signatures of the input commit to
+func (in *PartialInput) subScript() []byte {
+	if in.RedeemScript != nil {
+		return in.RedeemScript
+	}
+	return in.PrevOutput.LockingScript
+}
+
+// canSign reports whether the key is one of the keys the input needs
+func (in *PartialInput) canSign(pubKey []byte) bool {
+	if pubKeyHash, ok := script.ExtractPubKeyHash(in.PrevOutput.LockingScript); ok {
+		return bytes.Equal(pubKeyHash, wallet.PublicKeyHash(pubKey))
+	}
+
+	if _, pubKeys, ok := script.ExtractMultiSig(in.RedeemScript); ok {
+		for _, candidate := range pubKeys {
+			if bytes.Equal(candidate, pubKey) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Sign adds a signature with privateKey to every input it can unlock and
+// returns the number of signed inputs
+func (ptx *PartialTransaction) Sign(privateKey wallet.PrivateKey, pubKey []byte, hashType SigHashType) (int, error) {
+	if err := ptx.check(); err != nil {
+		return 0, err
+	}
+
+	signed := 0
+	hashes := NewSigHashes(&ptx.Tx, ptx.prevTxs())
+
+	for inId := range ptx.Inputs {
+		in := &ptx.Inputs[inId]
+		if !in.canSign(pubKey) {
+			continue
+		}
+
+		hash, err := ptx.Tx.SignatureHash(inId, in.subScript(), in.PrevOutput.Value, hashType, hashes)
+		if err != nil {
+			return signed, err
+		}
+
+		signature, err := privateKey.Sign(hash)
+		if err != nil {
+			return signed, err
+		}
+		signature = append(signature, byte(hashType))
+
+		if in.Signatures == nil {
+			in.Signatures = make(map[string][]byte)
+		}
+		in.Signatures[hex.EncodeToString(pubKey)] = signature
+		signed++
+	}
+
+	return signed, nil
+}
+
+// Finalize builds the unlocking scripts out of the collected signatures and
+// returns the transaction, ready to be broadcast
+func (ptx *PartialTransaction) Finalize() (*Transaction, error) {
+	if err := ptx.check(); err != nil {
+		return nil, err
+	}
+
+	tx := ptx.Tx
+	tx.Inputs = append([]TxInput{}, ptx.Tx.Inputs...)
+
+	for inId := range tx.Inputs {
+		in := ptx.Inputs[inId]
+
+		if _, ok := script.ExtractPubKeyHash(in.PrevOutput.LockingScript); ok {
+			for pubKey, signature := range in.Signatures {
+				key, err := hex.DecodeString(pubKey)
+				if err != nil {
+					return nil, fmt.Errorf("input %d has a malformed public key: %s", inId, err)
+				}
+				if in.canSign(key) {
+					tx.Inputs[inId].UnlockingScript = script.PayToPubKeyHashUnlock(signature, key)
+				}
+			}
+			continue
+		}
+
+		required, pubKeys, ok := script.ExtractMultiSig(in.RedeemScript)
+		if !ok {
+			return nil, fmt.Errorf("input %d spends an output of unknown type", inId)
+		}
+
+		var signatures [][]byte
+		for _, pubKey := range pubKeys {
+			if signature, ok := in.Signatures[hex.EncodeToString(pubKey)]; ok && len(signatures) < required {
+				signatures = append(signatures, signature)
+			}
+		}
+		tx.Inputs[inId].UnlockingScript = script.MultiSigUnlock(signatures, in.RedeemScript)
+	}
+
+	if !tx.Verify(ptx.prevTxs()) {
+		return nil, errors.New("transaction is not fully signed")
+	}
+
+	return &tx, nil
+}
+
+// Fee is what the inputs spend beyond the outputs of the transaction
+func (ptx *PartialTransaction) Fee() int {
+	fee := 0
+	for _, in := range ptx.Inputs {
+		fee += in.PrevOutput.Value
+	}
+	for _, out := range ptx.Tx.Outputs {
+		fee -= out.Value
+	}
+	return fee
+}
+
+// Join adds the inputs of other, a transaction paying the same outputs, so
+// that several parties can fund them together. Signatures committing to all
+// inputs do not survive, contributors sign with SigHashAnyoneCanPay
+func (ptx *PartialTransaction) Join(other *PartialTransaction) error {
+	if err := ptx.check(); err != nil {
+		return err
+	}
+	if err := other.check(); err != nil {
+		return err
+	}
+	if ptx.Tx.LockTime != other.Tx.LockTime || len(ptx.Tx.Outputs) != len(other.Tx.Outputs) {
+		return errors.New("transactions pay different outputs")
+	}
+	for i, out := range ptx.Tx.Outputs {
+		if out.Value != other.Tx.Outputs[i].Value || !bytes.Equal(out.LockingScript, other.Tx.Outputs[i].LockingScript) {
+			return errors.New("transactions pay different outputs")
+		}
+	}
+
+	for i, in := range other.Tx.Inputs {
+		known := -1
+		for inId, mine := range ptx.Tx.Inputs {
+			if bytes.Equal(mine.ID, in.ID) && mine.Out == in.Out {
+				known = inId
+			}
+		}
+
+		if known < 0 {
+			ptx.Tx.Inputs = append(ptx.Tx.Inputs, in)
+			ptx.Inputs = append(ptx.Inputs, other.Inputs[i])
+			continue
+		}
+
+		// Both sides may have collected signatures for the same input
+		if ptx.Inputs[known].Signatures == nil {
+			ptx.Inputs[known].Signatures = make(map[string][]byte)
+		}
+		for pubKey, signature := range other.Inputs[i].Signatures {
+			ptx.Inputs[known].Signatures[pubKey] = signature
+		}
+		for _, hint := range other.Inputs[i].Hints {
+			ptx.Inputs[known].AddHint(hint)
+		}
+	}
+
+	ptx.Tx.ID = ptx.Tx.Hash()
+	return nil
+}
+
+// check makes sure every input of the transaction is described, files are
+// read from untrusted sources
+func (ptx *PartialTransaction) check() error {
+	if len(ptx.Inputs) != len(ptx.Tx.Inputs) {
+		return fmt.Errorf("partial transaction describes %d of its %d inputs", len(ptx.Inputs), len(ptx.Tx.Inputs))
+	}
+	for inId, in := range ptx.Tx.Inputs {
+		if in.Out < 0 {
+			return fmt.Errorf("input %d spends a negative output index", inId)
+		}
+	}
+	return nil
+}
+
+// prevTxs rebuilds the previous transactions expected by Transaction.Verify
+// out of the outputs carried by the inputs
+func (ptx *PartialTransaction) prevTxs() map[string]Transaction {
+	prevTxs := make(map[string]Transaction)
+
+	for inId, in := range ptx.Tx.Inputs {
+		txID := hex.EncodeToString(in.ID)
+		prevTx, ok := prevTxs[txID]
+		if !ok {
+			prevTx = Transaction{ID: in.ID}
+		}
+
+		for len(prevTx.Outputs) <= in.Out {
+			prevTx.Outputs = append(prevTx.Outputs, TxOutput{})
+		}
+		prevTx.Outputs[in.Out] = ptx.Inputs[inId].PrevOutput
+		prevTxs[txID] = prevTx
+	}
+
+	return prevTxs
+}
+
+func (ptx *PartialTransaction) Serialize() []byte {
+	var content bytes.Buffer
+
+	encoder := gob.NewEncoder(&content)
+	err := encoder.Encode(ptx)
+	Handle(err)
+
+	return content.Bytes()
+}
+
+func DeserializePartialTransaction(data []byte) (*PartialTransaction, error) {
+	var ptx PartialTransaction
+
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&ptx); err != nil {
+		return nil, err
+	}
+	if err := ptx.check(); err != nil {
+		return nil, err
+	}
+
+	return &ptx, nil
+}
@@ -0,0 +1,62 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"gitlab.com/thesepehrm/first-blockchain/script"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+// partialTx wraps a transaction spending two outputs of privateKey the way
+// NewPartialTransaction does
+func partialTx(privateKey wallet.PrivateKey) (*PartialTransaction, map[string]Transaction) {
+	tx, prevTxs := spendingTx(privateKey, 2, 1)
+
+	ptx := &PartialTransaction{Tx: *tx}
+	for _, in := range tx.Inputs {
+		prevOut := prevTxs[hex.EncodeToString(in.ID)].Outputs[in.Out]
+		ptx.Inputs = append(ptx.Inputs, PartialInput{PrevOutput: prevOut})
+	}
+	return ptx, prevTxs
+}
+
+func TestPartialTransactionMalformed(t *testing.T) {
+	privateKey, _ := wallet.NewKeyPair(wallet.KeyP256)
+	ptx, _ := partialTx(privateKey)
+	ptx.Inputs = ptx.Inputs[:1]
+
+	if _, err := ptx.Sign(privateKey, privateKey.PublicKey(), SigHashAll); err == nil {
+		t.Error("Sign accepted a transaction with an undescribed input")
+	}
+	if _, err := DeserializePartialTransaction(ptx.Serialize()); err == nil {
+		t.Error("DeserializePartialTransaction accepted a transaction with an undescribed input")
+	}
+}
+
+func TestPartialTransactionAmounts(t *testing.T) {
+	privateKey, _ := wallet.NewKeyPair(wallet.KeyP256)
+
+	ptx, prevTxs := partialTx(privateKey)
+	if _, err := ptx.Sign(privateKey, privateKey.PublicKey(), SigHashAll); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := ptx.Finalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A signer shown an understated value for the second input makes a
+	// signature of the first one that does not verify
+	understated, _ := partialTx(privateKey)
+	understated.Inputs[1].PrevOutput.Value = 1
+	if _, err := understated.Sign(privateKey, privateKey.PublicKey(), SigHashAll); err != nil {
+		t.Fatal(err)
+	}
+
+	signature := understated.Inputs[0].Signatures[hex.EncodeToString(privateKey.PublicKey())]
+	tx.Inputs[0].UnlockingScript = script.PayToPubKeyHashUnlock(signature, privateKey.PublicKey())
+	if tx.Verify(prevTxs) {
+		t.Error("a signature over an understated value of another input verified")
+	}
+}
@@ -0,0 +1,77 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sync"
+)
+
+// DefaultSigCacheSize is the number of signatures a node remembers
+const DefaultSigCacheSize = 50000
+
+// SigCache remembers the signatures found valid when transactions entered
+// the memory pool, so that they are not verified again when they are mined
+type SigCache struct {
+	mutex      sync.RWMutex
+	valid      map[[sha256.Size]byte]struct{}
+	maxEntries int
+}
+
+func NewSigCache(maxEntries int) *SigCache {
+	return &SigCache{valid: make(map[[sha256.Size]byte]struct{}), maxEntries: maxEntries}
+}
+
+// sigCacheKey hashes the fields prefixed with their lengths, so that bytes
+// moved from one field to the next make another key
+func sigCacheKey(hash, signature, pubKey []byte) [sha256.Size]byte {
+	var content bytes.Buffer
+	writeBytes(&content, hash)
+	writeBytes(&content, signature)
+	writeBytes(&content, pubKey)
+
+	return sha256.Sum256(content.Bytes())
+}
+
+// Exists reports whether the signature of hash by pubKey was found valid.
+// Entries found while validating a block are dropped when erase is set, a
+// mined signature is not checked again
+func (cache *SigCache) Exists(hash, signature, pubKey []byte, erase bool) bool {
+	key := sigCacheKey(hash, signature, pubKey)
+
+	if erase {
+		cache.mutex.Lock()
+		defer cache.mutex.Unlock()
+
+		_, ok := cache.valid[key]
+		delete(cache.valid, key)
+		return ok
+	}
+
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	_, ok := cache.valid[key]
+	return ok
+}
+
+// Add records a valid signature, an arbitrary entry is evicted when the
+// cache is full
+func (cache *SigCache) Add(hash, signature, pubKey []byte) {
+	if cache.maxEntries <= 0 {
+		return
+	}
+	key := sigCacheKey(hash, signature, pubKey)
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if len(cache.valid) >= cache.maxEntries {
+		// Map iteration order is random, which makes evictions hard to
+		// predict for an attacker
+		for evicted := range cache.valid {
+			delete(cache.valid, evicted)
+			break
+		}
+	}
+	cache.valid[key] = struct{}{}
+}
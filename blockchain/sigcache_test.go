@@ -0,0 +1,44 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"gitlab.com/thesepehrm/first-blockchain/script"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+func TestSigCacheKeyFields(t *testing.T) {
+	if sigCacheKey([]byte{1, 2}, []byte{3}, []byte{4}) == sigCacheKey([]byte{1}, []byte{2, 3}, []byte{4}) {
+		t.Error("bytes moved between fields give the same key")
+	}
+}
+
+func TestSigCacheAfterParsing(t *testing.T) {
+	privateKey, _ := wallet.NewKeyPair(wallet.KeyP256)
+	tx, prevTxs := spendingTx(privateKey, 1, 1)
+	if err := tx.Sign(privateKey, prevTxs, SigHashAll); err != nil {
+		t.Fatal(err)
+	}
+
+	pushes, _ := script.ExtractPushes(tx.Inputs[0].UnlockingScript)
+	signature, pubKey := pushes[0], pushes[1]
+	subScript := prevTxs[hex.EncodeToString(tx.Inputs[0].ID)].Outputs[0].LockingScript
+
+	cache := NewSigCache(DefaultSigCacheSize)
+	checker := txChecker{tx: tx, amount: 10, hashes: NewSigHashes(tx, prevTxs), cache: cache}
+	if !checker.CheckSig(signature, pubKey, subScript) {
+		t.Fatal("CheckSig rejected a valid signature")
+	}
+
+	// A malformed key cached as valid is still refused
+	badKey := append([]byte{}, pubKey...)
+	badKey[1] = 0x05
+	hash, _ := tx.SignatureHash(0, subScript, 10, SigHashAll, checker.hashes)
+	rawSignature, _, _ := splitSignature(signature)
+	cache.Add(hash, rawSignature, badKey)
+
+	if checker.CheckSig(signature, badKey, subScript) {
+		t.Error("CheckSig accepted a malformed key found in the cache")
+	}
+}
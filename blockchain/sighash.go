@@ -0,0 +1,180 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// SigHashType selects the parts of a transaction a signature commits to. It
+// is appended to every signature so that verification hashes the same parts
+type SigHashType byte
+
+const (
+	// SigHashAll commits to every input and output
+	SigHashAll SigHashType = 0x01
+	// SigHashNone commits to the inputs only, anyone may pick the outputs
+	SigHashNone SigHashType = 0x02
+	// SigHashSingle commits to the inputs and to the output at the index of
+	// the signed input
+	SigHashSingle SigHashType = 0x03
+	// SigHashAnyoneCanPay is combined with the others to commit to the signed
+	// input only, so that more inputs can be added afterwards
+	SigHashAnyoneCanPay SigHashType = 0x80
+
+	sigHashMask = 0x1f
+)
+
+var sigHashNames = map[SigHashType]string{
+	SigHashAll:    "ALL",
+	SigHashNone:   "NONE",
+	SigHashSingle: "SINGLE",
+}
+
+func (t SigHashType) String() string {
+	name, ok := sigHashNames[t&sigHashMask]
+	if !ok {
+		return fmt.Sprintf("UNKNOWN(%#x)", byte(t))
+	}
+	if t&SigHashAnyoneCanPay != 0 {
+		name += "|ANYONECANPAY"
+	}
+	return name
+}
+
+// ParseSigHashType reads names such as ALL or SINGLE|ANYONECANPAY
+func ParseSigHashType(name string) (SigHashType, error) {
+	parts := strings.Split(strings.ToUpper(name), "|")
+
+	var hashType SigHashType
+	for t, typeName := range sigHashNames {
+		if parts[0] == typeName {
+			hashType = t
+		}
+	}
+
+	switch {
+	case hashType == 0 || len(parts) > 2:
+		return 0, fmt.Errorf("unknown signature hash type %q", name)
+	case len(parts) == 2 && parts[1] != "ANYONECANPAY":
+		return 0, fmt.Errorf("unknown signature hash flag %q", parts[1])
+	case len(parts) == 2:
+		hashType |= SigHashAnyoneCanPay
+	}
+
+	return hashType, nil
+}
+
+// Valid reports whether the type is one of the defined combinations
+func (t SigHashType) Valid() bool {
+	_, ok := sigHashNames[t&^SigHashAnyoneCanPay]
+	return ok
+}
+
+// splitSignature separates a signature from the hash type appended to it
+func splitSignature(signature []byte) ([]byte, SigHashType, bool) {
+	if len(signature) < 2 {
+		return nil, 0, false
+	}
+
+	hashType := SigHashType(signature[len(signature)-1])
+	return signature[:len(signature)-1], hashType, hashType.Valid()
+}
+
+// SigHashes are the parts of the signature hashes shared by all the inputs
+// of a transaction. Computing them once keeps signing and verifying linear in
+// the number of inputs, instead of hashing the whole transaction per input
+type SigHashes struct {
+	Prevouts  []byte // Hash of the outpoints spent by every input
+	Amounts   []byte // Hash of the values of the outputs spent by every input
+	Sequences []byte // Hash of the sequences of every input
+	Outputs   []byte // Hash of every output
+}
+
+// NewSigHashes hashes the inputs and outputs of tx, prevTxs hold the outputs
+// its inputs spend
+func NewSigHashes(tx *Transaction, prevTxs map[string]Transaction) *SigHashes {
+	var prevouts, amounts, sequences, outputs bytes.Buffer
+
+	for _, in := range tx.Inputs {
+		writeBytes(&prevouts, in.ID)
+		writeInt(&prevouts, int64(in.Out))
+		writeInt(&sequences, int64(in.Sequence))
+
+		// Unknown outputs fail verification anyway
+		amount := 0
+		if prevTx := prevTxs[hex.EncodeToString(in.ID)]; in.Out >= 0 && in.Out < len(prevTx.Outputs) {
+			amount = prevTx.Outputs[in.Out].Value
+		}
+		writeInt(&amounts, int64(amount))
+	}
+	for _, out := range tx.Outputs {
+		writeOutput(&outputs, out)
+	}
+
+	prevoutsHash := sha256.Sum256(prevouts.Bytes())
+	amountsHash := sha256.Sum256(amounts.Bytes())
+	sequencesHash := sha256.Sum256(sequences.Bytes())
+	outputsHash := sha256.Sum256(outputs.Bytes())
+
+	return &SigHashes{prevoutsHash[:], amountsHash[:], sequencesHash[:], outputsHash[:]}
+}
+
+// SignatureHash is the hash a signature of the input at inId commits to.
+// subScript is the script the signature is checked against, usually the
+// locking script of the spent output, and amount the value of that output.
+// Unless SigHashAnyoneCanPay is set the values spent by the other inputs are
+// committed to through hashes as well: a signer told a wrong amount for any
+// input makes an invalid signature instead of paying an unexpected fee.
+// Inputs without an output at their index can not be signed with
+// SigHashSingle
+func (tx *Transaction) SignatureHash(inId int, subScript []byte, amount int, hashType SigHashType, hashes *SigHashes) ([]byte, error) {
+	zeroHash := make([]byte, sha256.Size)
+	anyoneCanPay := hashType&SigHashAnyoneCanPay != 0
+	baseType := hashType &^ SigHashAnyoneCanPay
+
+	// Other inputs are left out with SigHashAnyoneCanPay, and their
+	// sequences with SigHashNone and SigHashSingle so that their owners can
+	// still update them
+	prevouts, amounts, sequences := hashes.Prevouts, hashes.Amounts, hashes.Sequences
+	if anyoneCanPay {
+		prevouts, amounts = zeroHash, zeroHash
+	}
+	if anyoneCanPay || baseType != SigHashAll {
+		sequences = zeroHash
+	}
+
+	outputs := hashes.Outputs
+	switch baseType {
+	case SigHashNone:
+		outputs = zeroHash
+	case SigHashSingle:
+		if inId >= len(tx.Outputs) {
+			return nil, fmt.Errorf("input %d has no output at its index to sign with %s", inId, hashType)
+		}
+		var single bytes.Buffer
+		writeOutput(&single, tx.Outputs[inId])
+		singleHash := sha256.Sum256(single.Bytes())
+		outputs = singleHash[:]
+	}
+
+	in := tx.Inputs[inId]
+
+	var preimage bytes.Buffer
+	preimage.Write(prevouts)
+	preimage.Write(amounts)
+	preimage.Write(sequences)
+	writeBytes(&preimage, in.ID)
+	writeInt(&preimage, int64(in.Out))
+	writeBytes(&preimage, subScript)
+	writeInt(&preimage, int64(amount))
+	writeInt(&preimage, int64(in.Sequence))
+	preimage.Write(outputs)
+	writeInt(&preimage, tx.LockTime)
+	preimage.WriteByte(byte(hashType))
+
+	hash := sha256.Sum256(preimage.Bytes())
+	return hash[:], nil
+}
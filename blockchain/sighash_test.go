@@ -0,0 +1,102 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"gitlab.com/thesepehrm/first-blockchain/script"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+// spendingTx returns a transaction with one input per output of a previous
+// transaction paying to privateKey, and outputs outputs
+func spendingTx(privateKey wallet.PrivateKey, inputs, outputs int) (*Transaction, map[string]Transaction) {
+	lockingScript := script.PayToPubKeyHash(wallet.PublicKeyHash(privateKey.PublicKey()))
+
+	prevTx := Transaction{Inputs: []TxInput{{[]byte{}, -1, []byte("bench"), MaxSequence}}}
+	for i := 0; i < inputs; i++ {
+		prevTx.Outputs = append(prevTx.Outputs, TxOutput{10, lockingScript})
+	}
+	prevTx.ID = prevTx.Hash()
+
+	tx := Transaction{}
+	for i := 0; i < inputs; i++ {
+		tx.Inputs = append(tx.Inputs, TxInput{prevTx.ID, i, nil, MaxSequence})
+	}
+	for i := 0; i < outputs; i++ {
+		tx.Outputs = append(tx.Outputs, TxOutput{1, lockingScript})
+	}
+	tx.ID = tx.Hash()
+
+	return &tx, map[string]Transaction{hex.EncodeToString(prevTx.ID): prevTx}
+}
+
+func TestSignSingleWithoutOutput(t *testing.T) {
+	privateKey, _ := wallet.NewKeyPair(wallet.KeyP256)
+	tx, prevTxs := spendingTx(privateKey, 2, 1)
+
+	if _, err := tx.SignatureHash(1, prevTxs[hex.EncodeToString(tx.Inputs[1].ID)].Outputs[1].LockingScript, 10, SigHashSingle, NewSigHashes(tx, prevTxs)); err == nil {
+		t.Error("SignatureHash of SINGLE without a matching output succeeded")
+	}
+	if err := tx.Sign(privateKey, prevTxs, SigHashSingle); err == nil {
+		t.Error("Sign left the input without a matching output unsigned")
+	}
+
+	if err := tx.Sign(privateKey, prevTxs, SigHashAll); err != nil {
+		t.Fatal(err)
+	}
+	if !tx.Verify(prevTxs) {
+		t.Error("Verify rejected the transaction signed with ALL")
+	}
+}
+
+var benchmarkInputs = []int{1, 10, 100, 1000}
+
+// reportPerInput adds the time spent per input, which stays flat when the
+// work grows linearly with the number of inputs
+func reportPerInput(b *testing.B, start time.Time, inputs int) {
+	b.ReportMetric(float64(time.Since(start).Nanoseconds())/float64(b.N*inputs), "ns/input")
+}
+
+func BenchmarkSign(b *testing.B) {
+	privateKey, _ := wallet.NewKeyPair(wallet.KeyP256)
+
+	for _, inputs := range benchmarkInputs {
+		b.Run(fmt.Sprintf("inputs=%d", inputs), func(b *testing.B) {
+			tx, prevTxs := spendingTx(privateKey, inputs, 2)
+
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				if err := tx.Sign(privateKey, prevTxs, SigHashAll); err != nil {
+					b.Fatal(err)
+				}
+			}
+			reportPerInput(b, start, inputs)
+		})
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	privateKey, _ := wallet.NewKeyPair(wallet.KeyP256)
+
+	for _, inputs := range benchmarkInputs {
+		b.Run(fmt.Sprintf("inputs=%d", inputs), func(b *testing.B) {
+			tx, prevTxs := spendingTx(privateKey, inputs, 2)
+			if err := tx.Sign(privateKey, prevTxs, SigHashAll); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				if !tx.Verify(prevTxs) {
+					b.Fatal("signed transaction rejected")
+				}
+			}
+			reportPerInput(b, start, inputs)
+		})
+	}
+}
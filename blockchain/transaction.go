@@ -2,24 +2,25 @@ package blockchain
 
 import (
 	"bytes"
-	"crypto/ecdsa"
-	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/gob"
 	"encoding/hex"
 	"fmt"
 	"log"
-	"math/big"
 	"strings"
 
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+	"gitlab.com/thesepehrm/first-blockchain/script"
 	"gitlab.com/thesepehrm/first-blockchain/wallet"
 )
 
 type Transaction struct {
-	ID      []byte
-	Inputs  []TxInput
-	Outputs []TxOutput
+	ID       []byte
+	Inputs   []TxInput
+	Outputs  []TxOutput
+	LockTime int64 // Block height or unix timestamp, see LockTimeThreshold
 }
 
 func (tx Transaction) String() string {
@@ -30,14 +31,22 @@ func (tx Transaction) String() string {
 		lines = append(lines, fmt.Sprintf("\tInput %d", inputId))
 		lines = append(lines, fmt.Sprintf("\t\tTXID: %x", input.ID))
 		lines = append(lines, fmt.Sprintf("\t\tOut: %d", input.Out))
-		lines = append(lines, fmt.Sprintf("\t\tSignature: %x", input.Signature))
-		lines = append(lines, fmt.Sprintf("\t\tPubKey: %x", input.PubKey))
+		if tx.IsCoinbase() {
+			lines = append(lines, fmt.Sprintf("\t\tData: %x", input.UnlockingScript))
+		} else {
+			lines = append(lines, fmt.Sprintf("\t\tUnlockingScript: %s", script.Disassemble(input.UnlockingScript)))
+		}
+		lines = append(lines, fmt.Sprintf("\t\tSequence: %d", input.Sequence))
 	}
 
 	for outputId, output := range tx.Outputs {
 		lines = append(lines, fmt.Sprintf("\tOutput %d", outputId))
 		lines = append(lines, fmt.Sprintf("\t\tValue: %d", output.Value))
-		lines = append(lines, fmt.Sprintf("\t\tPubKeyHash: %x", output.PubKeyHash))
+		lines = append(lines, fmt.Sprintf("\t\tLockingScript: %s", script.Disassemble(output.LockingScript)))
+	}
+
+	if tx.LockTime != 0 {
+		lines = append(lines, fmt.Sprintf("\tLockTime: %d", tx.LockTime))
 	}
 
 	return strings.Join(lines, "\n")
@@ -63,52 +72,47 @@ func CoinbaseTx(to, data string) *Transaction {
 		data = fmt.Sprintf("%x", randData)
 	}
 
-	txin := TxInput{[]byte{}, -1, nil, []byte(data)}
-	txout := NewTxOutput(10, to)
+	txout, err := NewTxOutput(chaincfg.Active.Subsidy, to)
+	Handle(err)
+
+	return coinbaseTx(data, *txout)
+}
+
+// coinbaseTx creates the coinbase paying out, data fills its input
+func coinbaseTx(data string, out TxOutput) *Transaction {
+	txin := TxInput{[]byte{}, -1, []byte(data), MaxSequence}
 
-	tx := Transaction{nil, []TxInput{txin}, []TxOutput{*txout}}
+	tx := Transaction{nil, []TxInput{txin}, []TxOutput{out}, 0}
 
 	tx.ID = tx.Hash()
 
 	return &tx
 }
 
-func NewTransaction(from, to string, amount int, UTXO *UTXOSet, nodeID string) *Transaction {
-	var inputs []TxInput
-	var outputs []TxOutput
-
+func NewTransaction(from, to string, amount int, lockTime int64, UTXO *UTXOSet, nodeID string) *Transaction {
 	wallets, err := wallet.CreateWallets(nodeID)
 	Handle(err)
-	w := wallets.GetWallet(from)
-	pubKeyHash := wallet.PublicKeyHash(w.PublicKey)
-
-	acc, validOutputs := UTXO.FindSpendableOutputs(pubKeyHash, amount)
-
-	if acc < amount {
-		log.Panic("Error: not enough funds")
-	}
-
-	for txid, outs := range validOutputs {
-		txID, err := hex.DecodeString(txid)
-		Handle(err)
 
-		for _, out := range outs {
-			input := TxInput{txID, out, nil, w.PublicKey}
-			inputs = append(inputs, input)
-		}
-	}
+	builder := NewTxBuilder(UTXO, []string{from})
+	builder.LockTime = lockTime
+	tx, err := builder.AddOutput(to, amount).Build()
+	Handle(err)
 
-	outputs = append(outputs, *NewTxOutput(amount, to))
+	err = UTXO.Blockchain.SignTransactionWithWallets(tx, wallets)
+	Handle(err)
 
-	if acc > amount {
-		outputs = append(outputs, *NewTxOutput(acc-amount, from))
-	}
+	return tx
+}
 
-	tx := Transaction{nil, inputs, outputs}
-	tx.ID = tx.Hash()
-	UTXO.Blockchain.SignTransaction(&tx, w.PrivateKey)
+// NewUnsignedTransaction creates a transaction spending from any kind of
+// address, it is signed separately, e.g. through a PartialTransaction
+func NewUnsignedTransaction(from, to string, amount int, lockTime int64, UTXO *UTXOSet) *Transaction {
+	builder := NewTxBuilder(UTXO, []string{from})
+	builder.LockTime = lockTime
+	tx, err := builder.AddOutput(to, amount).Build()
+	Handle(err)
 
-	return &tx
+	return tx
 }
 
 func (tx *Transaction) IsCoinbase() bool {
@@ -125,13 +129,62 @@ func (tx Transaction) Serialize() []byte {
 	return content.Bytes()
 }
 
+// Encode is the canonical byte representation hashes commit to. Unlike the
+// gob output of Serialize, it does not depend on what else the process
+// encoded before, so every node computes the same hashes
+func (tx *Transaction) Encode() []byte {
+	var content bytes.Buffer
+
+	writeInt(&content, int64(len(tx.Inputs)))
+	for _, in := range tx.Inputs {
+		writeBytes(&content, in.ID)
+		writeInt(&content, int64(in.Out))
+		writeBytes(&content, in.UnlockingScript)
+		writeInt(&content, int64(in.Sequence))
+	}
+
+	writeInt(&content, int64(len(tx.Outputs)))
+	for _, out := range tx.Outputs {
+		writeOutput(&content, out)
+	}
+
+	writeInt(&content, tx.LockTime)
+
+	return content.Bytes()
+}
+
+func writeInt(content *bytes.Buffer, num int64) {
+	err := binary.Write(content, binary.BigEndian, num)
+	Handle(err)
+}
+
+// writeBytes writes data prefixed with its length
+func writeBytes(content *bytes.Buffer, data []byte) {
+	writeInt(content, int64(len(data)))
+	content.Write(data)
+}
+
+func writeOutput(content *bytes.Buffer, out TxOutput) {
+	writeInt(content, int64(out.Value))
+	writeBytes(content, out.LockingScript)
+}
+
+// Hash is the id of the transaction. It leaves out the unlocking scripts of
+// spending inputs, so that signing does not change the id and signatures can
+// not be altered into another one. The data of a coinbase input is kept, it
+// tells apart coinbases paying the same output
 func (tx *Transaction) Hash() []byte {
 	var hash [32]byte
 
-	tempTx := *tx
-	tempTx.ID = []byte{}
+	txCopy := *tx
+	if !tx.IsCoinbase() {
+		txCopy.Inputs = make([]TxInput, len(tx.Inputs))
+		for i, in := range tx.Inputs {
+			txCopy.Inputs[i] = TxInput{ID: in.ID, Out: in.Out, Sequence: in.Sequence}
+		}
+	}
 
-	hash = sha256.Sum256(tempTx.Serialize())
+	hash = sha256.Sum256(txCopy.Encode())
 
 	return hash[:]
 }
@@ -144,39 +197,54 @@ func (tx *Transaction) TrimmedCopy() Transaction {
 
 	outputs = append(outputs, tx.Outputs...)
 
-	txCopy := Transaction{[]byte{}, inputs, outputs}
+	txCopy := Transaction{[]byte{}, inputs, outputs, tx.LockTime}
 	return txCopy
 
 }
 
-func (tx *Transaction) Sign(privateKey ecdsa.PrivateKey, prevTxs map[string]Transaction) {
+// Sign signs every input spending a pay-to-pubkey-hash output locked to the
+// given key, committing to the parts of the transaction hashType selects
+func (tx *Transaction) Sign(privateKey wallet.PrivateKey, prevTxs map[string]Transaction, hashType SigHashType) error {
 	if tx.IsCoinbase() {
-		return
+		return nil
 	}
 
-	for _, in := range prevTxs {
+	for _, in := range tx.Inputs {
 		if prevTxs[hex.EncodeToString(in.ID)].ID == nil {
 			log.Panic("Error: Previous transaction does not exist")
 		}
 	}
 
-	txCopy := tx.TrimmedCopy()
+	pubKey := privateKey.PublicKey()
+	pubKeyHash := wallet.PublicKeyHash(pubKey)
 
-	for inId, in := range txCopy.Inputs {
-		prevTx := prevTxs[hex.EncodeToString(in.ID)]
-		txCopy.Inputs[inId].Signature = nil
-		txCopy.Inputs[inId].PubKey = prevTx.Outputs[in.Out].PubKeyHash
-		txCopy.ID = txCopy.Hash()
-		txCopy.Inputs[inId].PubKey = nil
+	hashes := NewSigHashes(tx, prevTxs)
+	for inId, in := range tx.Inputs {
+		prevOut := prevTxs[hex.EncodeToString(in.ID)].Outputs[in.Out]
 
-		r, s, err := ecdsa.Sign(rand.Reader, &privateKey, txCopy.ID)
-		Handle(err)
-		signature := append(r.Bytes(), s.Bytes()...)
+		if !prevOut.isLockedWith(pubKeyHash) {
+			continue
+		}
+
+		hash, err := tx.SignatureHash(inId, prevOut.LockingScript, prevOut.Value, hashType, hashes)
+		if err != nil {
+			return err
+		}
+
+		signature, err := privateKey.Sign(hash)
+		if err != nil {
+			return err
+		}
+		signature = append(signature, byte(hashType))
 
-		tx.Inputs[inId].Signature = signature
+		tx.Inputs[inId].UnlockingScript = script.PayToPubKeyHashUnlock(signature, pubKey)
 	}
+
+	return nil
 }
 
+// Verify runs the unlocking script of every input against the locking script
+// of the output it spends
 func (tx *Transaction) Verify(prevTxs map[string]Transaction) bool {
 	if tx.IsCoinbase() {
 		return true
@@ -188,33 +256,13 @@ func (tx *Transaction) Verify(prevTxs map[string]Transaction) bool {
 		}
 	}
 
-	txCopy := tx.TrimmedCopy()
-	curve := elliptic.P256()
-
-	for inId, in := range txCopy.Inputs {
-		prevTx := prevTxs[hex.EncodeToString(in.ID)]
-		txCopy.Inputs[inId].Signature = nil
-		txCopy.Inputs[inId].PubKey = prevTx.Outputs[in.Out].PubKeyHash
-		txCopy.ID = txCopy.Hash()
-		txCopy.Inputs[inId].PubKey = nil
-
-		r := big.Int{}
-		s := big.Int{}
-		// first half of the signature is r and the second half is s
-		sigLen := len(in.Signature)
-		r.SetBytes(in.Signature[:(sigLen / 2)])
-		s.SetBytes(in.Signature[(sigLen / 2):])
-
-		x := big.Int{}
-		y := big.Int{}
-
-		pubLen := len(in.PubKey)
-		x.SetBytes(in.PubKey[:(pubLen / 2)])
-		y.SetBytes(in.PubKey[(pubLen / 2):])
-
-		rawPublicKey := ecdsa.PublicKey{Curve: curve, X: &x, Y: &y}
+	checks, ok := tx.scriptChecks(prevTxs)
+	if !ok {
+		return false
+	}
 
-		if !ecdsa.Verify(&rawPublicKey, txCopy.ID, &r, &s) {
+	for _, check := range checks {
+		if err := check.run(nil, false); err != nil {
 			return false
 		}
 	}
@@ -4,46 +4,107 @@ import (
 	"bytes"
 	"encoding/gob"
 
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+	"gitlab.com/thesepehrm/first-blockchain/script"
 	"gitlab.com/thesepehrm/first-blockchain/wallet"
 )
 
 type TxInput struct {
-	ID        []byte
-	Out       int // Output index
-	Signature []byte
-	PubKey    []byte
+	ID              []byte
+	Out             int    // Output index
+	UnlockingScript []byte // Satisfies the locking script of the spent output
+	Sequence        uint32
 }
 
 type TxOutputs struct {
-	Outputs []TxOutput
+	Outputs   []TxOutput
+	Height    int   // Height of the block that confirmed the outputs
+	Timestamp int64 // Timestamp of the block that confirmed the outputs
+	Indexes   []int // Position of each output in its transaction
+}
+
+// Index returns the position the i-th unspent output has in its transaction
+func (outs *TxOutputs) Index(i int) int {
+	if outs.Indexes == nil {
+		return i
+	}
+	return outs.Indexes[i]
+}
+
+// Output returns the unspent output found at the given position of its
+// transaction, false when it is spent or does not exist
+func (outs *TxOutputs) Output(index int) (TxOutput, bool) {
+	for i, out := range outs.Outputs {
+		if outs.Index(i) == index {
+			return out, true
+		}
+	}
+	return TxOutput{}, false
+}
+
+// Add appends the output found at the given position of its transaction
+func (outs *TxOutputs) Add(index int, out TxOutput) {
+	outs.Outputs = append(outs.Outputs, out)
+	outs.Indexes = append(outs.Indexes, index)
 }
 
 type TxOutput struct {
-	Value      int
-	PubKeyHash []byte
+	Value         int
+	LockingScript []byte
 }
 
-func NewTxOutput(value int, address string) *TxOutput {
+func NewTxOutput(value int, address string) (*TxOutput, error) {
 	txo := new(TxOutput)
 	txo.Value = value
-	txo.Lock([]byte(address))
+	if err := txo.Lock([]byte(address)); err != nil {
+		return nil, err
+	}
 
-	return txo
+	return txo, nil
 }
 
+// UsesKey reports whether the input unlocks a pay-to-pubkey-hash output with
+// the key hashing to pubKeyHash
 func (in *TxInput) UsesKey(pubKeyHash []byte) bool {
-	lockHash := wallet.PublicKeyHash(in.PubKey)
+	pushes, ok := script.ExtractPushes(in.UnlockingScript)
+	if !ok || len(pushes) != 2 {
+		return false
+	}
+
+	lockHash := wallet.PublicKeyHash(pushes[1])
 	return bytes.Equal(lockHash, pubKeyHash)
 }
 
-func (out *TxOutput) Lock(address []byte) {
-	pubKeyHash := wallet.DecodeBase58(address)
-	pubKeyHash = pubKeyHash[1 : len(pubKeyHash)-4]
-	out.PubKeyHash = pubKeyHash
+func (out *TxOutput) Lock(address []byte) error {
+	version, hash, err := wallet.DecodeAddress(string(address))
+	if err != nil {
+		return err
+	}
+
+	if version == chaincfg.Active.ScriptHashAddrID {
+		out.LockingScript = script.PayToScriptHash(hash)
+	} else {
+		out.LockingScript = script.PayToPubKeyHash(hash)
+	}
+	return nil
+}
+
+// isLockedWith reports whether the output pays to the given public key hash,
+// or to the given script hash for pay-to-script-hash outputs
+func (out *TxOutput) isLockedWith(pubKeyHash []byte) bool {
+	lockHash := out.LockHash()
+	return lockHash != nil && bytes.Equal(pubKeyHash, lockHash)
 }
 
-func (out *TxOutput) isLockedWith(pubkeyHash []byte) bool {
-	return bytes.Equal(pubkeyHash, out.PubKeyHash)
+// LockHash returns the public key or script hash an output is locked to
+func (out *TxOutput) LockHash() []byte {
+	if pubKeyHash, ok := script.ExtractPubKeyHash(out.LockingScript); ok {
+		return pubKeyHash
+	}
+	if scriptHash, ok := script.ExtractScriptHash(out.LockingScript); ok {
+		return scriptHash
+	}
+	return nil
 }
 
 func (outs *TxOutputs) Serialize() []byte {
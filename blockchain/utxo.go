@@ -3,9 +3,10 @@ package blockchain
 import (
 	"bytes"
 	"encoding/hex"
-	"log"
+	"fmt"
 
 	"github.com/dgraph-io/badger"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
 )
 
 var (
@@ -70,10 +71,44 @@ func (u *UTXOSet) FindUnspentTransactions(pubKeyHash []byte) []TxOutput {
 	return UTXOs
 }
 
+// FindSpendableOutputs picks outputs locked with pubKeyHash worth at least
+// amount using the default coin selection, it returns their value and their
+// indexes by hex transaction id
 func (u UTXOSet) FindSpendableOutputs(pubKeyHash []byte, amount int) (int, map[string][]int) {
 	unspentOuts := make(map[string][]int)
 	accumulated := 0
 
+	candidates := u.FindUnspentOutputs([][]byte{pubKeyHash})
+	selected, err := LargestFirst{}.Select(candidates, amount)
+	if err != nil {
+		// Not enough funds, report everything there is
+		selected = candidates
+	}
+
+	for _, unspent := range selected {
+		txID := hex.EncodeToString(unspent.TxID)
+		accumulated += unspent.Output.Value
+		unspentOuts[txID] = append(unspentOuts[txID], unspent.Index)
+	}
+
+	return accumulated, unspentOuts
+
+}
+
+// UnspentOutput is an output that has not been spent yet, together with the
+// position needed to reference it from an input
+type UnspentOutput struct {
+	TxID   []byte
+	Index  int
+	Output TxOutput
+	Height int
+}
+
+// FindUnspentOutputs returns every unspent output locked with one of the
+// given public key or script hashes
+func (u UTXOSet) FindUnspentOutputs(lockHashes [][]byte) []UnspentOutput {
+	var unspent []UnspentOutput
+
 	db := u.Blockchain.Database
 
 	err := db.View(func(txn *badger.Txn) error {
@@ -82,89 +117,169 @@ func (u UTXOSet) FindSpendableOutputs(pubKeyHash []byte, amount int) (int, map[s
 		for iterator.Seek(utxoPrefix); iterator.ValidForPrefix(utxoPrefix); iterator.Next() {
 			var outputs TxOutputs
 
-			txID := hex.EncodeToString(bytes.TrimPrefix(iterator.Item().Key(), utxoPrefix))
+			txID := append([]byte{}, bytes.TrimPrefix(iterator.Item().Key(), utxoPrefix)...)
 
 			err := iterator.Item().Value(func(val []byte) error {
 				outputs = DeserializeOutputs(val)
 				return nil
 			})
-
 			Handle(err)
 
-			for outID, out := range outputs.Outputs {
-				if out.isLockedWith(pubKeyHash) && accumulated < amount {
-					accumulated += out.Value
-					unspentOuts[txID] = append(unspentOuts[txID], outID)
-
-					if accumulated >= out.Value {
+			for i, out := range outputs.Outputs {
+				for _, lockHash := range lockHashes {
+					if out.isLockedWith(lockHash) {
+						unspent = append(unspent, UnspentOutput{txID, outputs.Index(i), out, outputs.Height})
 						break
 					}
 				}
-
 			}
-
 		}
-
 		return nil
 	})
 	Handle(err)
 
-	return accumulated, unspentOuts
+	return unspent
+}
+
+// FindOutputs returns the unspent outputs of a transaction
+func (u UTXOSet) FindOutputs(txID []byte) (TxOutputs, error) {
+	var outputs TxOutputs
+
+	err := u.Blockchain.Database.View(func(txn *badger.Txn) error {
+		record, err := txn.Get(append(utxoPrefix, txID...))
+		if err != nil {
+			return err
+		}
+
+		return record.Value(func(val []byte) error {
+			outputs = DeserializeOutputs(val)
+			return nil
+		})
+	})
+
+	return outputs, err
+}
+
+// PrevOutputs collects the unspent outputs referenced by the relative locked
+// inputs of tx, as expected by Transaction.CheckSequenceLocks
+func (u UTXOSet) PrevOutputs(tx *Transaction) map[string]TxOutputs {
+	prevOutputs := make(map[string]TxOutputs)
 
+	for _, in := range tx.Inputs {
+		if !in.HasRelativeLock() {
+			continue
+		}
+		if outputs, err := u.FindOutputs(in.ID); err == nil {
+			prevOutputs[hex.EncodeToString(in.ID)] = outputs
+		}
+	}
+
+	return prevOutputs
 }
 
-func (u *UTXOSet) Update(block *Block) {
+// CheckInputs makes sure every input of tx spends an unspent output that is
+// not in spent, and that the outputs of tx do not exceed its inputs. On
+// success the outpoints of tx are added to spent and its fee is returned
+func (u UTXOSet) CheckInputs(tx *Transaction, spent map[string]bool) (int, error) {
+	inputValue := 0
+	outpoints := make(map[string]bool)
+
+	for _, in := range tx.Inputs {
+		outpoint := wallet.Outpoint(in.ID, in.Out)
+		if spent[outpoint] || outpoints[outpoint] {
+			return 0, fmt.Errorf("output %s is spent twice", outpoint)
+		}
+
+		outputs, err := u.FindOutputs(in.ID)
+		if err != nil {
+			return 0, fmt.Errorf("output %s is not unspent", outpoint)
+		}
+		out, ok := outputs.Output(in.Out)
+		if !ok {
+			return 0, fmt.Errorf("output %s is not unspent", outpoint)
+		}
+
+		outpoints[outpoint] = true
+		inputValue += out.Value
+	}
+
+	outputValue := 0
+	for _, out := range tx.Outputs {
+		// Checked one by one so that the sum can not overflow
+		if out.Value < 0 || out.Value > inputValue-outputValue {
+			return 0, fmt.Errorf("outputs exceed the inputs worth %d", inputValue)
+		}
+		outputValue += out.Value
+	}
+
+	for outpoint := range outpoints {
+		spent[outpoint] = true
+	}
+
+	return inputValue - outputValue, nil
+}
+
+// Update applies a block extending the chain of the set, spending the outputs
+// its inputs reference and adding its new outputs. Nothing changes when one
+// of the inputs is not in the set
+func (u *UTXOSet) Update(block *Block) error {
 	db := u.Blockchain.Database
 
-	err := db.Update(func(txn *badger.Txn) error {
+	return db.Update(func(txn *badger.Txn) error {
 		for _, tx := range block.Transactions {
 
 			if !tx.IsCoinbase() {
 				for _, input := range tx.Inputs {
-					updatedOutputs := TxOutputs{}
-					prefixedInputID := append(utxoPrefix, input.ID...)
+					prefixedInputID := append(append([]byte{}, utxoPrefix...), input.ID...)
 					record, err := txn.Get(prefixedInputID)
+					if err != nil {
+						return fmt.Errorf("output %x:%d is not unspent: %s", input.ID, input.Out, err)
+					}
 
-					Handle(err)
 					var outputs TxOutputs
 					err = record.Value(func(val []byte) error {
 						outputs = DeserializeOutputs(val)
 						return nil
 					})
-					Handle(err)
+					if err != nil {
+						return err
+					}
+					if _, ok := outputs.Output(input.Out); !ok {
+						return fmt.Errorf("output %x:%d is not unspent", input.ID, input.Out)
+					}
 
-					for outIdx, out := range outputs.Outputs {
-						if input.Out != outIdx {
-							updatedOutputs.Outputs = append(outputs.Outputs, out)
+					updatedOutputs := TxOutputs{Height: outputs.Height, Timestamp: outputs.Timestamp}
+					for i, out := range outputs.Outputs {
+						if outputs.Index(i) != input.Out {
+							updatedOutputs.Add(outputs.Index(i), out)
 						}
 					}
 
 					if len(updatedOutputs.Outputs) == 0 {
-						if err := txn.Delete(prefixedInputID); err != nil {
-							log.Panic(err)
-						}
+						err = txn.Delete(prefixedInputID)
 					} else {
-						if err := txn.Set(prefixedInputID, updatedOutputs.Serialize()); err != nil {
-							log.Panic(err)
-						}
+						err = txn.Set(prefixedInputID, updatedOutputs.Serialize())
+					}
+					if err != nil {
+						return err
 					}
 				}
 
 			}
 
-			newOutputs := TxOutputs{}
-			newOutputs.Outputs = append(newOutputs.Outputs, tx.Outputs...)
+			newOutputs := TxOutputs{Height: block.Height, Timestamp: block.Timestamp}
+			for outIdx, out := range tx.Outputs {
+				newOutputs.Add(outIdx, out)
+			}
 
-			txID := append(utxoPrefix, tx.ID...)
+			txID := append(append([]byte{}, utxoPrefix...), tx.ID...)
 			if err := txn.Set(txID, newOutputs.Serialize()); err != nil {
-				log.Panic(err)
+				return err
 			}
 		}
 
 		return nil
 	})
-	Handle(err)
-
 }
 
 func (u UTXOSet) ReIndex() {
@@ -178,8 +293,9 @@ func (u UTXOSet) ReIndex() {
 			key, err := hex.DecodeString(txID)
 			Handle(err)
 			key = append(utxoPrefix, key...)
-			err = txn.Set(key, txOutputs.Serialize())
-			return err
+			if err := txn.Set(key, txOutputs.Serialize()); err != nil {
+				return err
+			}
 		}
 		return nil
 	})
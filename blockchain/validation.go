@@ -0,0 +1,148 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+)
+
+// MaxFutureBlockTime is how far ahead of the clock of the node the timestamp
+// of a block may be
+const MaxFutureBlockTime = 2 * time.Hour
+
+// ValidateBlock checks the consensus rules a received block has to satisfy
+// before it is added to the chain, the UTXO set is expected to reflect the
+// chain the block extends
+func (chain *BlockChain) ValidateBlock(block *Block) error {
+	UTXOSet := UTXOSet{chain}
+
+	// The hash covers the transactions through their merkle root, which
+	// takes at least one
+	if len(block.Transactions) == 0 || !block.Transactions[0].IsCoinbase() {
+		return fmt.Errorf("block %x does not start with a coinbase", block.Hash)
+	}
+
+	// Everything below relies on the hash, which the proof of work and the
+	// checkpoints vouch for, covering the content of the block
+	pow := NewProof(block)
+	if !bytes.Equal(block.Hash, pow.Hash()) {
+		return fmt.Errorf("block %x does not match its content", block.Hash)
+	}
+	if !pow.Validate() {
+		return fmt.Errorf("block %x misses the proof of work target", block.Hash)
+	}
+
+	if err := chain.checkHeader(block); err != nil {
+		return err
+	}
+	if err := chain.checkCheckpoints(block); err != nil {
+		return err
+	}
+	verifyInputs := !chain.assumeValid(block)
+
+	// Outputs created earlier in the same block are confirmed by it
+	blockOutputs := make(map[string]TxOutputs)
+
+	// Outputs spent by the transactions of the block so far
+	spent := make(map[string]bool)
+
+	fees := 0
+	var coinbase *Transaction
+
+	// Scripts are checked last, in parallel, once the cheaper rules passed
+	var checks []scriptCheck
+
+	for _, tx := range block.Transactions {
+		txID := hex.EncodeToString(tx.ID)
+		if !bytes.Equal(tx.ID, tx.Hash()) {
+			return fmt.Errorf("transaction %x does not hash to its id", tx.ID)
+		}
+		if _, ok := blockOutputs[txID]; ok {
+			return fmt.Errorf("transaction %x is in the block twice", tx.ID)
+		}
+		if _, err := UTXOSet.FindOutputs(tx.ID); err == nil {
+			return fmt.Errorf("transaction %x has unspent outputs already", tx.ID)
+		}
+
+		if !tx.IsFinal(block.Height, block.Timestamp) {
+			return fmt.Errorf("transaction %x is not final at height %d", tx.ID, block.Height)
+		}
+
+		if tx.IsCoinbase() {
+			if coinbase != nil {
+				return fmt.Errorf("block has more than one coinbase")
+			}
+			coinbase = tx
+		} else {
+			fee, err := UTXOSet.CheckInputs(tx, spent)
+			if err != nil {
+				return fmt.Errorf("transaction %x: %s", tx.ID, err)
+			}
+			fees += fee
+		}
+
+		if verifyInputs && !tx.IsCoinbase() {
+			prevTxs, err := chain.prevTransactions(tx)
+			if err != nil {
+				return fmt.Errorf("transaction %x: %s", tx.ID, err)
+			}
+
+			txChecks, ok := tx.scriptChecks(prevTxs)
+			if !ok {
+				return fmt.Errorf("transaction %x has invalid inputs", tx.ID)
+			}
+			checks = append(checks, txChecks...)
+		}
+
+		prevOutputs := UTXOSet.PrevOutputs(tx)
+		for _, in := range tx.Inputs {
+			prevID := hex.EncodeToString(in.ID)
+			if outputs, ok := blockOutputs[prevID]; ok {
+				prevOutputs[prevID] = outputs
+			}
+		}
+
+		if err := tx.CheckSequenceLocks(block.Height, block.Timestamp, prevOutputs); err != nil {
+			return fmt.Errorf("transaction %x: %s", tx.ID, err)
+		}
+
+		blockOutputs[txID] = TxOutputs{Height: block.Height, Timestamp: block.Timestamp}
+	}
+
+	if coinbase == nil {
+		return fmt.Errorf("block has no coinbase")
+	}
+	reward := chaincfg.Active.Subsidy + fees
+	for _, out := range coinbase.Outputs {
+		if out.Value < 0 || out.Value > reward {
+			return fmt.Errorf("coinbase pays more than the subsidy and fees of %d", chaincfg.Active.Subsidy+fees)
+		}
+		reward -= out.Value
+	}
+
+	return verifyScripts(checks, chain.SigCache, true)
+}
+
+// checkHeader makes sure the height and timestamp of block, which lock times
+// are checked against, follow its parent
+func (chain *BlockChain) checkHeader(block *Block) error {
+	parent, err := chain.GetBlock(block.PrevHash)
+	if err != nil {
+		return fmt.Errorf("parent %x is unknown", block.PrevHash)
+	}
+
+	if block.Height != parent.Height+1 {
+		return fmt.Errorf("height %d does not follow the height %d of its parent", block.Height, parent.Height)
+	}
+	if block.Timestamp < parent.Timestamp {
+		return fmt.Errorf("timestamp %d is before the timestamp %d of its parent", block.Timestamp, parent.Timestamp)
+	}
+	if time.Unix(block.Timestamp, 0).After(time.Now().Add(MaxFutureBlockTime)) {
+		return fmt.Errorf("timestamp %d is too far in the future", block.Timestamp)
+	}
+
+	return nil
+}
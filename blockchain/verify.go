@@ -0,0 +1,99 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"gitlab.com/thesepehrm/first-blockchain/script"
+)
+
+// VerifyWorkers is the number of goroutines verifying the scripts of a block
+var VerifyWorkers = runtime.NumCPU()
+
+// scriptCheck is the verification of one input script against the locking
+// script of the output it spends
+type scriptCheck struct {
+	tx      *Transaction
+	inId    int
+	prevOut TxOutput
+	hashes  *SigHashes
+}
+
+func (check scriptCheck) run(cache *SigCache, inBlock bool) error {
+	checker := &txChecker{tx: check.tx, inId: check.inId, amount: check.prevOut.Value, hashes: check.hashes, cache: cache, inBlock: inBlock}
+	in := check.tx.Inputs[check.inId]
+
+	if err := script.Execute(in.UnlockingScript, check.prevOut.LockingScript, checker); err != nil {
+		return fmt.Errorf("transaction %x input %d: %s", check.tx.ID, check.inId, err)
+	}
+	return nil
+}
+
+// scriptChecks lists the checks of every input of tx, false when an input
+// spends an output its previous transaction does not have
+func (tx *Transaction) scriptChecks(prevTxs map[string]Transaction) ([]scriptCheck, bool) {
+	hashes := NewSigHashes(tx, prevTxs)
+
+	checks := make([]scriptCheck, 0, len(tx.Inputs))
+	for inId, in := range tx.Inputs {
+		prevTx := prevTxs[hex.EncodeToString(in.ID)]
+		if in.Out < 0 || in.Out >= len(prevTx.Outputs) {
+			return nil, false
+		}
+
+		checks = append(checks, scriptCheck{tx, inId, prevTx.Outputs[in.Out], hashes})
+	}
+
+	return checks, true
+}
+
+// verifyScripts runs the checks on VerifyWorkers goroutines and returns the
+// first failure, the remaining checks are abandoned once one fails
+func verifyScripts(checks []scriptCheck, cache *SigCache, inBlock bool) error {
+	workers := VerifyWorkers
+	if workers > len(checks) {
+		workers = len(checks)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan scriptCheck)
+	done := make(chan struct{})
+
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		failure  error
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for check := range jobs {
+				if err := check.run(cache, inBlock); err != nil {
+					failOnce.Do(func() {
+						failure = err
+						close(done)
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, check := range checks {
+		select {
+		case jobs <- check:
+		case <-done:
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return failure
+}
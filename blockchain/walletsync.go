@@ -0,0 +1,159 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"sort"
+	"time"
+
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+// ErrRescanCancelled is returned when a rescan is stopped before the tip
+var ErrRescanCancelled = errors.New("rescan cancelled")
+
+// MemoryPoolExpiry is how long nodes keep a transaction that is not mined
+const MemoryPoolExpiry = 72 * time.Hour
+
+// SyncWallet records the transactions of the blocks the wallet history has
+// not seen yet, releases the outputs of pending transactions nodes forgot
+// about, and returns the number of transactions recorded
+func (chain *BlockChain) SyncWallet(wallets *wallet.Wallets) int {
+	// The chain got shorter than what was seen, start over
+	if wallets.SyncedHeight > chain.GetBestHeight()+1 {
+		wallets.SyncedHeight = 0
+	}
+
+	recorded, _ := chain.RescanWallet(wallets, wallets.SyncedHeight, nil, nil)
+	wallets.ReleaseExpired(time.Now(), MemoryPoolExpiry)
+
+	return recorded
+}
+
+// RescanWallet walks the blocks from height from up to the tip, recording the
+// transactions and unspent outputs of the wallet, and returns the number of
+// transactions recorded. progress is called after every block when set, and
+// closing cancel stops the scan with ErrRescanCancelled. The wallet keeps
+// what was found before the scan stopped
+func (chain *BlockChain) RescanWallet(wallets *wallet.Wallets, from int, progress func(height, bestHeight int), cancel <-chan struct{}) (int, error) {
+	bestHeight := chain.GetBestHeight()
+	if from < 0 {
+		from = 0
+	}
+
+	// Collect the hashes first, the iterator walks back from the tip
+	var hashes [][]byte
+	iter := chain.Iterator()
+	for {
+		block := iter.Next()
+		if block.Height < from {
+			break
+		}
+		hashes = append(hashes, block.Hash)
+
+		if len(block.PrevHash) == 0 {
+			break
+		}
+	}
+
+	wallets.ForgetFrom(from)
+	lockHashes := wallets.LockHashes()
+	recorded := 0
+
+	for i := len(hashes) - 1; i >= 0; i-- {
+		block, err := chain.GetBlock(hashes[i])
+		if err != nil {
+			return recorded, err
+		}
+
+		select {
+		case <-cancel:
+			if from <= wallets.SyncedHeight {
+				wallets.SyncedHeight = block.Height
+			}
+			return recorded, ErrRescanCancelled
+		default:
+		}
+
+		for _, tx := range block.Transactions {
+			if chain.RecordWalletTransaction(wallets, lockHashes, tx, block.Height, block.Timestamp) {
+				recorded++
+			}
+			trackWalletOutputs(wallets, lockHashes, tx, block.Height)
+		}
+
+		if progress != nil {
+			progress(block.Height, bestHeight)
+		}
+	}
+
+	// Blocks between what was seen and from are still unseen otherwise
+	if from <= wallets.SyncedHeight {
+		wallets.SyncedHeight = bestHeight + 1
+	}
+
+	return recorded, nil
+}
+
+// RecordWalletTransaction adds tx to the wallet history if it pays to or
+// spends from one of lockHashes, height is -1 for unconfirmed transactions
+func (chain *BlockChain) RecordWalletTransaction(wallets *wallet.Wallets, lockHashes map[string]string, tx *Transaction, height int, timestamp int64) bool {
+	rec := wallet.TxRecord{ID: tx.ID, Coinbase: tx.IsCoinbase(), Height: height, Timestamp: timestamp}
+	involved := make(map[string]bool)
+
+	for _, out := range tx.Outputs {
+		if address, ok := lockHashes[hex.EncodeToString(out.LockHash())]; ok {
+			rec.Received += out.Value
+			involved[address] = true
+		}
+	}
+
+	if !tx.IsCoinbase() {
+		for _, in := range tx.Inputs {
+			prevTx, err := chain.FindTransactions(in.ID)
+			if err != nil {
+				continue
+			}
+			out := prevTx.Outputs[in.Out]
+			if address, ok := lockHashes[hex.EncodeToString(out.LockHash())]; ok {
+				rec.Sent += out.Value
+				involved[address] = true
+			}
+		}
+	}
+
+	if len(involved) == 0 {
+		return false
+	}
+
+	for address := range involved {
+		rec.Addresses = append(rec.Addresses, address)
+	}
+	sort.Strings(rec.Addresses)
+	wallets.RecordTransaction(rec)
+
+	return true
+}
+
+// trackWalletOutputs updates the unspent outputs of the wallet with the
+// outputs tx spends and creates
+func trackWalletOutputs(wallets *wallet.Wallets, lockHashes map[string]string, tx *Transaction, height int) {
+	if !tx.IsCoinbase() {
+		for _, in := range tx.Inputs {
+			wallets.SpendOutput(in.ID, in.Out, tx.ID)
+		}
+	}
+
+	for index, out := range tx.Outputs {
+		if address, ok := lockHashes[hex.EncodeToString(out.LockHash())]; ok {
+			wallets.AddUnspent(wallet.OutputRecord{
+				TxID:          tx.ID,
+				Index:         index,
+				Value:         out.Value,
+				LockingScript: out.LockingScript,
+				Address:       address,
+				Height:        height,
+			})
+		}
+	}
+}
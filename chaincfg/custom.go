@@ -0,0 +1,163 @@
+package chaincfg
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// bitcoinIDs are the address and private key prefixes of Bitcoin, custom
+// networks stay clear of them as well
+var bitcoinIDs = []byte{0x00, 0x05, 0x80, 0x6f, 0xc4, 0xef}
+
+// AddressIDs are the address and private key prefixes of the network, in the
+// same order for every network so that the prefixes of two networks line up
+func (params *Params) AddressIDs() []byte {
+	return []byte{params.PubKeyHashAddrID, params.Secp256k1PubKeyHashAddrID, params.Ed25519PubKeyHashAddrID, params.ScriptHashAddrID, params.PrivateKeyID}
+}
+
+// reservedIDs are the prefixes custom networks may not use, mapped to the
+// network owning them
+func reservedIDs() map[byte]string {
+	reserved := make(map[byte]string)
+	for _, id := range bitcoinIDs {
+		reserved[id] = "bitcoin"
+	}
+	for _, params := range networks {
+		for _, id := range params.AddressIDs() {
+			reserved[id] = params.Name
+		}
+	}
+	return reserved
+}
+
+// NewCustomParams derives a private network from base. Its magic, address
+// and private key prefixes and coin type are random and differ from those of
+// the built-in networks, so that keys and addresses do not carry over. The
+// genesis fields are left for the creator of the network to fill in
+func NewCustomParams(base *Params, name, port string) (*Params, error) {
+	if name == "" || name != filepath.Base(name) {
+		return nil, fmt.Errorf("invalid network name %q", name)
+	}
+	for _, params := range networks {
+		if params.Name == name {
+			return nil, fmt.Errorf("network %s already exists", name)
+		}
+	}
+
+	params := *base
+	params.Name = name
+	params.DefaultPort = port
+	params.Seeds = []string{"localhost:" + port}
+	params.DataDir = filepath.Join("./tmp", name)
+	params.GenesisCoinbaseData = name + " genesis"
+	params.GenesisLockingScript = nil
+	params.GenesisHash = nil
+	params.Checkpoints = nil
+
+	if _, err := rand.Read(params.Magic[:]); err != nil {
+		return nil, err
+	}
+
+	reserved := reservedIDs()
+	for _, id := range []*byte{&params.PubKeyHashAddrID, &params.Secp256k1PubKeyHashAddrID, &params.Ed25519PubKeyHashAddrID, &params.ScriptHashAddrID, &params.PrivateKeyID} {
+		for {
+			var random [1]byte
+			if _, err := rand.Read(random[:]); err != nil {
+				return nil, err
+			}
+			if _, ok := reserved[random[0]]; !ok {
+				*id = random[0]
+				reserved[random[0]] = name
+				break
+			}
+		}
+	}
+
+	for params.HDCoinType <= RegTestParams.HDCoinType {
+		var random [4]byte
+		if _, err := rand.Read(random[:]); err != nil {
+			return nil, err
+		}
+		// Below the hardened range of derivation indexes
+		params.HDCoinType = binary.BigEndian.Uint32(random[:]) &^ (1 << 31)
+	}
+
+	return &params, nil
+}
+
+// LoadParams reads the parameters of a network saved by Save
+func LoadParams(path string) (*Params, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var params Params
+	if err := json.Unmarshal(content, &params); err != nil {
+		return nil, err
+	}
+	if err := params.check(); err != nil {
+		return nil, err
+	}
+
+	// Hand edited files may list checkpoints in any order
+	sort.Slice(params.Checkpoints, func(i, j int) bool {
+		return params.Checkpoints[i].Height < params.Checkpoints[j].Height
+	})
+
+	return &params, nil
+}
+
+// check rejects parameters a node of the network could not run with, or that
+// let keys and addresses pass for those of a built-in network
+func (params *Params) check() error {
+	switch {
+	case params.Name == "" || len(params.GenesisHash) == 0:
+		return errors.New("network file has no name or genesis block")
+	case params.Magic == [4]byte{}:
+		return errors.New("network file has no magic")
+	case params.DefaultPort == "":
+		return errors.New("network file has no default port")
+	case params.Difficulty < 1 || params.Difficulty > 255:
+		return fmt.Errorf("invalid difficulty %d, it has to be between 1 and 255", params.Difficulty)
+	}
+
+	for _, builtIn := range networks {
+		if params.Magic == builtIn.Magic {
+			return fmt.Errorf("network file shares its magic with %s", builtIn.Name)
+		}
+		if params.HDCoinType == builtIn.HDCoinType {
+			return fmt.Errorf("network file shares its coin type with %s", builtIn.Name)
+		}
+	}
+
+	reserved := reservedIDs()
+	seen := make(map[byte]bool)
+	for _, id := range params.AddressIDs() {
+		if network, ok := reserved[id]; ok {
+			return fmt.Errorf("network file shares the prefix %#x with %s", id, network)
+		}
+		if seen[id] {
+			return fmt.Errorf("network file uses the prefix %#x twice", id)
+		}
+		seen[id] = true
+	}
+
+	return nil
+}
+
+// Save writes the parameters as JSON, nodes joining the network load them
+// with -network PATH
+func (params *Params) Save(path string) error {
+	content, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,220 @@
+package chaincfg
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Checkpoint is the hash of the block of the chain at a height
+type Checkpoint struct {
+	Height int
+	Hash   []byte
+}
+
+// Params are the rules and constants of one network. Nodes and wallets of
+// different networks use different magic bytes, data directories and address
+// prefixes, so that they can not mix by mistake
+type Params struct {
+	Name string
+
+	// Magic prefixes every message between nodes, messages of other
+	// networks are dropped
+	Magic [4]byte
+
+	// DefaultPort is the port of the node when NODE_ID is not set, Seeds
+	// are the nodes a new node first connects to
+	DefaultPort string
+	Seeds       []string
+
+	// DataDir holds the chain and wallet files of the network
+	DataDir string
+
+	// The genesis block is rebuilt from these fields, every node of the
+	// network starts its chain with it. GenesisHash guards against changes
+	GenesisCoinbaseData  string // Input data of the genesis coinbase
+	GenesisLockingScript []byte // Locking script of the genesis coinbase output
+	GenesisTimestamp     int64
+	GenesisNonce         int
+	GenesisHash          []byte
+
+	// Checkpoints are blocks known to be in the chain, ordered by height.
+	// Chains conflicting with them are rejected
+	Checkpoints []Checkpoint
+
+	// Difficulty is the number of leading zero bits of block hashes
+	Difficulty int
+
+	// GenerateSupported allows mining blocks on request with the generate
+	// command, regardless of the memory pool
+	GenerateSupported bool
+
+	// Subsidy is the amount paid by the coinbase of every block
+	Subsidy int
+
+	// Address prefixes
+	PubKeyHashAddrID          byte // P-256 keys
+	Secp256k1PubKeyHashAddrID byte // secp256k1 keys
+	Ed25519PubKeyHashAddrID   byte // Ed25519 keys
+	ScriptHashAddrID          byte // Scripts, e.g. multisig
+	PrivateKeyID              byte // Exported private keys
+
+	// HDCoinType is the coin type level of BIP44 derivation paths
+	HDCoinType uint32
+}
+
+// unspendableScript pays the genesis coinbases of the built-in networks to a
+// public key hash of zeros, no known key hashes to it
+var unspendableScript = hexBytes("76a914000000000000000000000000000000000000000088ac")
+
+// MainNetParams is the main network, the one used before networks existed
+var MainNetParams = Params{
+	Name:        "mainnet",
+	Magic:       [4]byte{0xd1, 0x5e, 0xb1, 0x0c},
+	DefaultPort: "3000",
+	Seeds:       []string{"localhost:3000"},
+	DataDir:     "./tmp",
+
+	GenesisCoinbaseData:  "Genesis",
+	GenesisLockingScript: unspendableScript,
+	GenesisTimestamp:     1792368000,
+	GenesisNonce:         420015,
+	GenesisHash:          hexBytes("0000030f35580a9e13b5c8d5403af26ca059ff8c6389a872e04ba26c1592ebf7"),
+
+	Checkpoints: []Checkpoint{
+		{0, hexBytes("0000030f35580a9e13b5c8d5403af26ca059ff8c6389a872e04ba26c1592ebf7")},
+	},
+
+	Difficulty: 20,
+	Subsidy:    10,
+
+	PubKeyHashAddrID:          0x37,
+	Secp256k1PubKeyHashAddrID: 0x3f,
+	Ed25519PubKeyHashAddrID:   0x21,
+	ScriptHashAddrID:          0x3a,
+	PrivateKeyID:              0xa5,
+
+	HDCoinType: 0,
+}
+
+// TestNetParams is the public test network, its coins have no value
+var TestNetParams = Params{
+	Name:        "testnet",
+	Magic:       [4]byte{0x7e, 0x57, 0xb1, 0x0c},
+	DefaultPort: "13000",
+	Seeds:       []string{"localhost:13000"},
+	DataDir:     "./tmp/testnet",
+
+	GenesisCoinbaseData:  "Testnet genesis",
+	GenesisLockingScript: unspendableScript,
+	GenesisTimestamp:     1792368000,
+	GenesisNonce:         1951,
+	GenesisHash:          hexBytes("000094d09ea2cb3f68641f59621a36ee75d33e6773f496b1c3b7f5879c350f8e"),
+
+	Checkpoints: []Checkpoint{
+		{0, hexBytes("000094d09ea2cb3f68641f59621a36ee75d33e6773f496b1c3b7f5879c350f8e")},
+	},
+
+	Difficulty: 16,
+	Subsidy:    10,
+
+	PubKeyHashAddrID:          0x7f,
+	Secp256k1PubKeyHashAddrID: 0x41,
+	Ed25519PubKeyHashAddrID:   0x23,
+	ScriptHashAddrID:          0xc2,
+	PrivateKeyID:              0xe5,
+
+	HDCoinType: 1,
+}
+
+// RegTestParams is a local network for regression tests
+var RegTestParams = Params{
+	Name:        "regtest",
+	Magic:       [4]byte{0x2e, 0x67, 0xb1, 0x0c},
+	DefaultPort: "23000",
+	Seeds:       []string{"localhost:23000"},
+	DataDir:     "./tmp/regtest",
+
+	GenesisCoinbaseData:  "Regtest genesis",
+	GenesisLockingScript: unspendableScript,
+	GenesisTimestamp:     1792368000,
+	GenesisNonce:         0,
+	GenesisHash:          hexBytes("27c1d77b1050d0bbb8c846948085ded44621ded2c562f5c0295b8cc9780b6988"),
+
+	Difficulty:        1, // Every other hash is a valid proof of work
+	GenerateSupported: true,
+	Subsidy:           10,
+
+	PubKeyHashAddrID:          0x7a,
+	Secp256k1PubKeyHashAddrID: 0x43,
+	Ed25519PubKeyHashAddrID:   0x25,
+	ScriptHashAddrID:          0xc6,
+	PrivateKeyID:              0xf0,
+
+	HDCoinType: 2,
+}
+
+var networks = []*Params{&MainNetParams, &TestNetParams, &RegTestParams}
+
+// Active is the network of this process, mainnet unless Select picked
+// another one
+var Active = &MainNetParams
+
+// Select makes the network with the given name the active one. Custom
+// networks are selected by the path of their parameters file
+func Select(name string) (*Params, error) {
+	for _, params := range networks {
+		if strings.EqualFold(params.Name, name) {
+			Active = params
+			return params, nil
+		}
+	}
+
+	if _, err := os.Stat(name); err != nil {
+		return nil, fmt.Errorf("unknown network %q, use mainnet, testnet, regtest or the file of a custom network", name)
+	}
+
+	params, err := LoadParams(name)
+	if err != nil {
+		return nil, fmt.Errorf("network file %s: %s", name, err)
+	}
+	Active = params
+	return params, nil
+}
+
+// CheckpointHash returns the hash of the checkpoint at height, if any
+func (params *Params) CheckpointHash(height int) ([]byte, bool) {
+	for _, checkpoint := range params.Checkpoints {
+		if checkpoint.Height == height {
+			return checkpoint.Hash, true
+		}
+	}
+	return nil, false
+}
+
+// LastCheckpoint is the highest checkpoint, nil when there is none
+func (params *Params) LastCheckpoint() *Checkpoint {
+	if len(params.Checkpoints) == 0 {
+		return nil
+	}
+	return &params.Checkpoints[len(params.Checkpoints)-1]
+}
+
+func hexBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// IsAddressID reports whether id prefixes addresses of the network
+func (params *Params) IsAddressID(id byte) bool {
+	switch id {
+	case params.PubKeyHashAddrID, params.Secp256k1PubKeyHashAddrID,
+		params.Ed25519PubKeyHashAddrID, params.ScriptHashAddrID:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package chaincfg
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// bitcoinMagics are the message prefixes of the Bitcoin mainnet, testnet and
+// regtest, nodes of those networks must not take our messages for theirs
+var bitcoinMagics = [][4]byte{
+	{0xf9, 0xbe, 0xb4, 0xd9},
+	{0x0b, 0x11, 0x09, 0x07},
+	{0xfa, 0xbf, 0xb5, 0xda},
+}
+
+func TestNetworksAreDistinct(t *testing.T) {
+	magics := make(map[[4]byte]string)
+	ids := make(map[byte]string)
+	coinTypes := make(map[uint32]string)
+
+	for _, magic := range bitcoinMagics {
+		magics[magic] = "bitcoin"
+	}
+	for _, id := range bitcoinIDs {
+		ids[id] = "bitcoin"
+	}
+
+	for _, params := range networks {
+		if other, ok := magics[params.Magic]; ok {
+			t.Errorf("%s shares its magic %x with %s", params.Name, params.Magic, other)
+		}
+		magics[params.Magic] = params.Name
+
+		for _, id := range params.AddressIDs() {
+			if other, ok := ids[id]; ok {
+				t.Errorf("%s shares the prefix %#x with %s", params.Name, id, other)
+			}
+			ids[id] = params.Name
+		}
+
+		if other, ok := coinTypes[params.HDCoinType]; ok {
+			t.Errorf("%s shares the coin type %d with %s", params.Name, params.HDCoinType, other)
+		}
+		coinTypes[params.HDCoinType] = params.Name
+	}
+}
+
+func TestCustomParamsAreDistinct(t *testing.T) {
+	reserved := reservedIDs()
+
+	for _, base := range networks {
+		params, err := NewCustomParams(base, "custom", "4000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		params.GenesisHash = []byte{1}
+
+		if err := params.check(); err != nil {
+			t.Errorf("network derived from %s: %s", base.Name, err)
+		}
+		for _, id := range params.AddressIDs() {
+			if network, ok := reserved[id]; ok {
+				t.Errorf("network derived from %s shares the prefix %#x with %s", base.Name, id, network)
+			}
+		}
+	}
+}
+
+func TestLoadParamsRejects(t *testing.T) {
+	valid, err := NewCustomParams(&RegTestParams, "custom", "4000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid.GenesisHash = []byte{1}
+
+	tests := []struct {
+		name   string
+		change func(params *Params)
+	}{
+		{"no difficulty", func(params *Params) { params.Difficulty = 0 }},
+		{"difficulty beyond the hash", func(params *Params) { params.Difficulty = 256 }},
+		{"no magic", func(params *Params) { params.Magic = [4]byte{} }},
+		{"no port", func(params *Params) { params.DefaultPort = "" }},
+		{"mainnet magic", func(params *Params) { params.Magic = MainNetParams.Magic }},
+		{"mainnet prefix", func(params *Params) { params.PubKeyHashAddrID = MainNetParams.PubKeyHashAddrID }},
+		{"bitcoin prefix", func(params *Params) { params.PrivateKeyID = 0x80 }},
+		{"testnet coin type", func(params *Params) { params.HDCoinType = TestNetParams.HDCoinType }},
+		{"no genesis", func(params *Params) { params.GenesisHash = nil }},
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "valid.json")
+	if err := valid.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadParams(path); err != nil {
+		t.Fatalf("valid network file rejected: %s", err)
+	}
+
+	for _, test := range tests {
+		params := *valid
+		test.change(&params)
+
+		path := filepath.Join(dir, "network.json")
+		if err := params.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadParams(path); err == nil {
+			t.Errorf("%s: network file accepted", test.name)
+		}
+	}
+}
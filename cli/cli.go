@@ -7,9 +7,12 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"sort"
 	"strconv"
+	"strings"
 
 	"gitlab.com/thesepehrm/first-blockchain/blockchain"
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
 	"gitlab.com/thesepehrm/first-blockchain/network"
 	"gitlab.com/thesepehrm/first-blockchain/wallet"
 )
@@ -18,21 +21,62 @@ import (
 type CommandLine struct{}
 
 func (cli *CommandLine) printHelp() {
+	println("Usage: [-network mainnet|testnet|regtest] COMMAND, NODE_ID is the port of the node, the default port of the network when unset")
 	println("Commands:")
-	println(" startnode [-miner] ADDRESS - Starts a node, -miner flag sets the node to be a miner")
-	println(" balance -address ADDRESS - Get the balance for the address")
-	println(" createchain -address ADDRESS - makes the blockchain and the address mines the genesis")
-	println(" send -from ADDRESS -to ADDRESS -amount AMOUNT - Sends some coin from an address to another address")
+	println(" startnode [-miner ADDRESS] [-assumevalid=false] - Starts a node, -miner flag sets the node to be a miner")
+	println("    Blocks conflicting with the checkpoints of the network are rejected. Blocks that the headers of a peer prove to lead to the last checkpoint skip their scripts, -assumevalid=false verifies them too")
+	println("    The built-in networks only checkpoint their genesis, every block is verified there unless a -network FILE lists later checkpoints")
+	println(" balance [-address ADDRESS] - Get the balance for the address, or for every address of the wallet")
+	println(" createchain -address ADDRESS -name NAME [-out FILE] - Creates a private network based on the selected one, the address mines its genesis")
+	println("    Nodes start the chain of the built-in networks by themselves, the private network is joined with -network FILE")
+	println(" send [-from ADDRESS,ADDRESS,...] -to ADDRESS -amount AMOUNT [-change ADDRESS] [-locktime LOCKTIME] [-coins STRATEGY] [-dryrun] [-memo MEMO] - Sends some coin from one or more addresses to another address")
+	println("    Without -from the whole wallet is spent from, change goes to -change or the first source address")
+	println("    LOCKTIME is a block height, or a unix timestamp when >= 500000000, before which the transaction can not be mined")
+	println("    STRATEGY is largest, smallest, bnb (exact match, no change) or random, -dryrun only prints the outputs that would be spent")
+	println(" sendmany [-from ADDRESS,...] (-to ADDRESS:AMOUNT,... | -file FILE) [-change ADDRESS] [-locktime LOCKTIME] [-coins STRATEGY] [-dryrun] [-memo MEMO] - Pays many recipients in a single transaction")
+	println("    FILE is a CSV file of address,amount lines or a JSON object mapping addresses to amounts")
+	println(" sendrawtx -hex TX - Broadcasts a serialized transaction, e.g. a time-locked one once it became final")
 	println(" print - Prints all of the blocks")
+	println(" generate -blocks N -to ADDRESS - Mines N blocks at once on regtest, with the memory pool of the running node of NODE_ID or else with coinbases only")
 	println("reindexutxo nodeID- Rebuilds the utxo database")
+	println("-----Offline signing-----")
+	println(" createtx -from ADDRESS -to ADDRESS -amount AMOUNT [-locktime LOCKTIME] -out FILE - Writes an unsigned transaction with the outputs it spends")
+	println(" signtx -in FILE [-out FILE] [-sighash ALL|NONE|SINGLE[|ANYONECANPAY]] - Shows the outputs and fee of a transaction written by createtx and signs it with the keys of this wallet once confirmed, no blockchain needed")
+	println(" jointx -in FILE,FILE,... -out FILE - Joins transactions paying the same outputs, inputs signed with ANYONECANPAY stay signed")
+	println(" broadcasttx -in FILE - Broadcasts a fully signed transaction")
 	println("-----Wallets-----")
-	println(" createwallet - Creates a new Wallet")
-	println(" listaddresses - Lists the addresses of our wallets")
+	println(" createwallet [-account N] [-type p256|secp256k1|ed25519] - Creates a new Wallet, derived from the seed phrase of the wallet when it has one")
+	println(" createhdwallet [-words 12|15|18|21|24] [-passphrase PASSPHRASE] [-type p256|secp256k1] - Creates a seed phrase all further keys are derived from")
+	println(" restorewallet -mnemonic \"WORDS\" [-passphrase PASSPHRASE] [-gaplimit N] [-type p256|secp256k1] - Restores a wallet from its seed phrase, scanning the chain for used addresses")
+	println(" showmnemonic - Prints the seed phrase of the wallet")
+	println(" listaddresses [-pubkeys] - Lists the addresses of our wallets, optionally with their public keys")
+	println(" dumpprivkey -address ADDRESS - Prints the private key of an address in a portable text format")
+	println(" importprivkey -key KEY [-rescan=false] - Imports a private key printed by dumpprivkey and looks up its outputs")
+	println(" importaddress -address ADDRESS [-rescan=false] - Watches an address without its private key")
+	println(" importpubkey -pubkey KEY [-rescan=false] - Watches the address of a public key, the key helps signers of createtx transactions")
+	println(" encryptwallet - Encrypts the wallet file with a passphrase, commands using the wallet ask for it afterwards")
+	println(" changepassphrase - Changes the passphrase of an encrypted wallet")
+	println(" unlock [-timeout SECONDS] - Lets commands of this node use the encrypted wallet without the passphrase for a while, the running node keeps the key in memory")
+	println(" lock - Ends an unlock before its timeout")
+	println("-----History-----")
+	println(" listtransactions [-count N] [-address ADDRESS] - Lists the transactions of the wallet with their confirmations")
+	println(" setlabel -address ADDRESS -label LABEL - Labels an address of the wallet or of a counterparty, an empty label removes it")
+	println(" setmemo -tx TXID -memo MEMO - Attaches a note to a transaction of the wallet")
+	println(" getaddressinfo -address ADDRESS - Prints what the wallet knows about an address")
+	println(" listunspent [-address ADDRESS] - Lists the unspent outputs of the wallet with their state")
+	println(" lockunspent -outpoints TXID:INDEX,... [-unlock] - Keeps outputs out of coin selection, or gives them back")
+	println(" rescan [-from HEIGHT] - Scans the chain from HEIGHT for the transactions and outputs of the wallet, interrupt to stop")
+	println(" setcoinselection -strategy STRATEGY - Sets the coin selection strategy used when sending from this wallet")
+	println("-----Multisig-----")
+	println(" createmultisig -required M -keys KEY,KEY,... - Creates an M-of-N multisig address, KEY is a public key or an address of this node")
+	println(" signpartial -from MULTISIG -to ADDRESS -amount AMOUNT - Creates a transaction spending from a multisig address and signs it with our keys")
+	println(" signpartial -tx TX - Adds the signatures of our keys to a partially signed transaction")
+	println(" combinesigs -txs TX,TX,... - Combines the signatures collected by co-signers")
 
 }
 
-func (cli *CommandLine) validateArgs() {
-	if len(os.Args) < 2 {
+func (cli *CommandLine) validateArgs(args []string) {
+	if len(args) < 1 {
 		cli.printHelp()
 		runtime.Goexit()
 	}
@@ -48,6 +92,11 @@ func (cli *CommandLine) startNode(nodeID, minerAddress string) {
 		}
 	}
 
+	go func() {
+		err := wallet.ServeSessions(nodeID)
+		fmt.Printf("Wallet can not be unlocked on this node: %s\n", err)
+	}()
+
 	network.Start(nodeID, minerAddress)
 
 }
@@ -74,14 +123,74 @@ func (cli *CommandLine) printChain(nodeID string) {
 	}
 }
 
-func (cli *CommandLine) createChain(address, nodeID string) {
+// createChain creates a private network derived from the active one, the
+// address mines its genesis. The parameters file is shared with the nodes
+// joining the network
+func (cli *CommandLine) createChain(address, name, out, nodeID string) {
 	if !wallet.ValidateAddress(address) {
 		log.Panic("Address is not valid")
 	}
 
-	chain := blockchain.InitBlockChain(address, nodeID)
+	params, err := chaincfg.NewCustomParams(chaincfg.Active, name, nodeID)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	if out == "" {
+		out = name + ".json"
+	}
+	if _, err := os.Stat(out); err == nil {
+		log.Panicf("%s already exists", out)
+	}
+
+	// The genesis address keeps its hash, with the prefix of the network
+	version, pubKeyHash, err := wallet.DecodeAddress(address)
+	wallet.Handle(err)
+	var genesisAddress []byte
+	for i, id := range chaincfg.Active.AddressIDs() {
+		if id == version {
+			genesisAddress = wallet.EncodeAddress(params.AddressIDs()[i], pubKeyHash)
+		}
+	}
+
+	// The key of the address, when this wallet has it, is copied to the network
+	var key *wallet.PrivateKey
+	if w, err := wallet.CreateWallets(nodeID); err == nil {
+		if found, ok := w.FindByPublicKeyHash(pubKeyHash); ok {
+			key = &found.PrivateKey
+		}
+	}
+
+	coinbase := blockchain.CoinbaseTx(address, params.GenesisCoinbaseData)
+	chaincfg.Active = params
+	genesis := blockchain.Genesis(coinbase)
+
+	params.GenesisLockingScript = coinbase.Outputs[0].LockingScript
+	params.GenesisTimestamp = genesis.Timestamp
+	params.GenesisNonce = genesis.Nonce
+	params.GenesisHash = genesis.Hash
+
+	err = params.Save(out)
+	blockchain.Handle(err)
+
+	// Rebuilt from the parameters, as every other node of the network will
+	chain := blockchain.InitBlockChain(blockchain.GenesisBlock(), nodeID)
 	chain.Database.Close()
-	fmt.Println("Finished!")
+
+	fmt.Printf("Genesis %x created, nodes join %s with -network %s\n", genesis.Hash, name, out)
+
+	// Addresses and exported keys of the network have their own prefixes,
+	// a key of this wallet is carried over as is
+	if key != nil {
+		defer holdWallets(nodeID)()
+
+		w := openWallets(nodeID)
+		w.ImportWallet(*key)
+		w.SaveFile(nodeID)
+		fmt.Printf("The key of %s is in the wallet of %s as %s, it spends the genesis coins\n", address, name, genesisAddress)
+		return
+	}
+	fmt.Printf("The genesis coins are paid to %s, the address of %s on %s\n", genesisAddress, address, name)
 }
 
 func (cli *CommandLine) getBalance(address string, nodeID string) {
@@ -95,8 +204,8 @@ func (cli *CommandLine) getBalance(address string, nodeID string) {
 
 	balance := 0
 
-	decoded := wallet.DecodeBase58([]byte(address))
-	pubKeyHash := decoded[1 : len(decoded)-4]
+	_, pubKeyHash, err := wallet.DecodeAddress(address)
+	wallet.Handle(err)
 	unspentTxs := UTXO.FindUnspentTransactions(pubKeyHash)
 
 	for _, out := range unspentTxs {
@@ -106,22 +215,62 @@ func (cli *CommandLine) getBalance(address string, nodeID string) {
 	fmt.Printf("Balance of %s: %d\n", address, balance)
 }
 
-func (cli *CommandLine) send(from string, to string, amount int, nodeID string) {
-	if !wallet.ValidateAddress(from) {
-		log.Panic("Source Address is not valid")
-	}
-	if !wallet.ValidateAddress(to) {
-		log.Panic("Destination Address is not valid")
-	}
+// getWalletBalance prints the balance of every address of the wallet,
+// keeping what the wallet can spend apart from what it only watches
+func (cli *CommandLine) getWalletBalance(nodeID string) {
+	w, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
 
 	chain := blockchain.ContinueBlockChain(nodeID)
 	UTXO := blockchain.UTXOSet{Blockchain: chain}
 	defer chain.Database.Close()
-	tx := blockchain.NewTransaction(from, to, amount, &UTXO, nodeID)
-	//chain.MineBlock([]*blockchain.Transaction{tx})
+
+	balanceOf := func(address string) int {
+		_, lockHash, err := wallet.DecodeAddress(address)
+		wallet.Handle(err)
+		balance := 0
+		for _, unspent := range UTXO.FindUnspentOutputs([][]byte{lockHash}) {
+			balance += unspent.Output.Value
+		}
+		return balance
+	}
+
+	spendable := 0
+	addresses := w.GetAllAddresses()
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		balance := balanceOf(address)
+		spendable += balance
+		fmt.Printf("%s: %d\n", address, balance)
+	}
+
+	for address := range w.MultiSigs {
+		fmt.Printf("%s: %d (multisig)\n", address, balanceOf(address))
+	}
+
+	watched := 0
+	watchOnly := w.GetWatchOnlyAddresses()
+	sort.Strings(watchOnly)
+	for _, address := range watchOnly {
+		balance := balanceOf(address)
+		watched += balance
+		fmt.Printf("%s: %d (watch-only)\n", address, balance)
+	}
+
+	fmt.Printf("Spendable: %d\n", spendable)
+	if len(watchOnly) > 0 {
+		fmt.Printf("Watch-only: %d\n", watched)
+	}
+}
+
+func (cli *CommandLine) send(from []string, to string, amount int, opts sendOptions, nodeID string) {
+	cli.pay(from, []payment{{to, amount}}, opts, nodeID)
+}
+
+func (cli *CommandLine) sendRawTx(rawTx string) {
+	tx := decodeTransaction(rawTx)
 	network.SendTX(network.KnownNodes[0], tx)
 	fmt.Printf("Sent Transaction #%s\n", hex.EncodeToString(tx.ID))
-
 }
 
 func (cli *CommandLine) reIndexUTXO(nodeID string) {
@@ -134,84 +283,391 @@ func (cli *CommandLine) reIndexUTXO(nodeID string) {
 	fmt.Printf("Done! There are %d UTXOs in the database\n", count)
 }
 
-func (cli *CommandLine) listAddresses(nodeID string) {
+func (cli *CommandLine) listAddresses(withPublicKeys bool, nodeID string) {
 	w, err := wallet.CreateWallets(nodeID)
 	wallet.Handle(err)
 
 	addresses := w.GetAllAddresses()
 	for _, address := range addresses {
-		fmt.Println(address)
+		line := address
+		if withPublicKeys {
+			line += fmt.Sprintf(" %x", w.GetWallet(address).PublicKey)
+		}
+		if path := w.GetWallet(address).Path; path != "" {
+			line += " " + path
+		}
+		fmt.Println(line)
+	}
+
+	for address, multiSig := range w.MultiSigs {
+		fmt.Printf("%s (%d of %d multisig)\n", address, multiSig.Required, len(multiSig.PublicKeys))
+	}
+
+	for address, watchOnly := range w.WatchOnly {
+		if withPublicKeys && watchOnly.PublicKey != nil {
+			fmt.Printf("%s %x (watch-only)\n", address, watchOnly.PublicKey)
+		} else {
+			fmt.Printf("%s (watch-only)\n", address)
+		}
+	}
+}
+
+func (cli *CommandLine) setCoinSelection(strategy string, nodeID string) {
+	defer holdWallets(nodeID)()
+
+	_, err := blockchain.NewCoinSelector(strategy)
+	blockchain.Handle(err)
+
+	w, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+	w.CoinSelection = strategy
+	w.SaveFile(nodeID)
+
+	fmt.Printf("Coin selection strategy set to %s\n", strategy)
+}
+
+// holdWallets takes the wallet lock of the node until the returned function
+// is called. Commands changing the wallet hold it from loading the file until
+// it is saved, so that they do not overwrite each other's changes
+func holdWallets(nodeID string) func() {
+	unlock, err := wallet.LockWallets(nodeID)
+	wallet.Handle(err)
+	return unlock
+}
+
+// openWallets loads the wallet file of the node for commands adding to it,
+// the wallet is empty when there is no file yet. Any other failure stops the
+// command before it could overwrite the file
+func openWallets(nodeID string) *wallet.Wallets {
+	w, err := wallet.CreateWallets(nodeID)
+	if err != nil && !os.IsNotExist(err) {
+		wallet.Handle(err)
 	}
+	return w
 }
 
-func (cli *CommandLine) createWallet(nodeID string) {
-	w, _ := wallet.CreateWallets(nodeID)
-	address := w.AddWallet()
+func (cli *CommandLine) createWallet(account uint, keyTypeName string, nodeID string) {
+	defer holdWallets(nodeID)()
+
+	w := openWallets(nodeID)
+
+	keyType := wallet.KeyP256
+	if keyTypeName != "" {
+		var err error
+		keyType, err = wallet.ParseKeyType(keyTypeName)
+		wallet.Handle(err)
+	}
+
+	var address string
+	if w.HD != nil {
+		if keyTypeName != "" && keyType != w.HD.KeyType {
+			log.Panicf("Keys of this wallet are derived from its seed phrase as %s keys", w.HD.KeyType)
+		}
+
+		var err error
+		address, err = w.AddHDWallet(uint32(account), wallet.ReceiveBranch)
+		wallet.Handle(err)
+	} else {
+		address = w.AddWallet(keyType)
+	}
 	w.SaveFile(nodeID)
 
 	fmt.Printf("New wallet address is: %s\n", address)
 }
 
 func (cli *CommandLine) Run() {
-	cli.validateArgs()
+	networkCommand := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	networkName := networkCommand.String("network", chaincfg.MainNetParams.Name, "Network to work on: mainnet, testnet or regtest")
+	err := networkCommand.Parse(os.Args[1:])
+	blockchain.Handle(err)
+
+	args := networkCommand.Args()
+	cli.validateArgs(args)
 
+	params, err := chaincfg.Select(*networkName)
+	if err != nil {
+		log.Panic(err)
+	}
+	network.UseNetwork(params)
+
+	// The node id is the port of the node
 	nodeID := os.Getenv("NODE_ID")
 	if nodeID == "" {
-		log.Panic("NODE_ID env is not set!")
-		runtime.Goexit()
+		nodeID = params.DefaultPort
 	}
 
+	wallet.PassphrasePrompt = readPassphrase
+
 	createChainCommand := flag.NewFlagSet("createchain", flag.ExitOnError)
 	createChainData := createChainCommand.String("address", "", "Address of the miner of the genesis")
+	createChainName := createChainCommand.String("name", "", "Name of the new network")
+	createChainOut := createChainCommand.String("out", "", "File to write the network parameters to, NAME.json when empty")
 
 	balanceCommand := flag.NewFlagSet("balance", flag.ExitOnError)
 	balanceData := balanceCommand.String("address", "", "Address of the wallet")
 
 	sendCommand := flag.NewFlagSet("send", flag.ExitOnError)
-	sendFrom := sendCommand.String("from", "", "Source wallet address")
+	sendFrom := sendCommand.String("from", "", "Comma separated source wallet addresses, all of them when empty")
+	sendChange := sendCommand.String("change", "", "Address receiving the change")
 	sendTo := sendCommand.String("to", "", "Destination wallet address")
 	sendAmount := sendCommand.Int("amount", 0, "Transfer amount")
+	sendLockTime := sendCommand.Int64("locktime", 0, "Block height or unix timestamp before which the transaction can not be mined")
+	sendCoins := sendCommand.String("coins", "", "Coin selection strategy, the wallet preference when empty")
+	sendDryRun := sendCommand.Bool("dryrun", false, "Print the outputs that would be spent without sending")
+	sendMemo := sendCommand.String("memo", "", "Note kept with the transaction in the wallet history")
+
+	sendManyCommand := flag.NewFlagSet("sendmany", flag.ExitOnError)
+	sendManyFrom := sendManyCommand.String("from", "", "Comma separated source wallet addresses, all of them when empty")
+	sendManyTo := sendManyCommand.String("to", "", "Comma separated ADDRESS:AMOUNT payments")
+	sendManyFile := sendManyCommand.String("file", "", "CSV or JSON file listing the payments")
+	sendManyChange := sendManyCommand.String("change", "", "Address receiving the change")
+	sendManyLockTime := sendManyCommand.Int64("locktime", 0, "Block height or unix timestamp before which the transaction can not be mined")
+	sendManyCoins := sendManyCommand.String("coins", "", "Coin selection strategy, the wallet preference when empty")
+	sendManyDryRun := sendManyCommand.Bool("dryrun", false, "Print the outputs that would be spent without sending")
+	sendManyMemo := sendManyCommand.String("memo", "", "Note kept with the transaction in the wallet history")
+
+	sendRawTxCommand := flag.NewFlagSet("sendrawtx", flag.ExitOnError)
+	sendRawTxData := sendRawTxCommand.String("hex", "", "Hex encoded transaction")
+
+	createTxCommand := flag.NewFlagSet("createtx", flag.ExitOnError)
+	createTxFrom := createTxCommand.String("from", "", "Source wallet address")
+	createTxTo := createTxCommand.String("to", "", "Destination wallet address")
+	createTxAmount := createTxCommand.Int("amount", 0, "Transfer amount")
+	createTxLockTime := createTxCommand.Int64("locktime", 0, "Block height or unix timestamp before which the transaction can not be mined")
+	createTxOut := createTxCommand.String("out", "", "File to write the unsigned transaction to")
+
+	signTxCommand := flag.NewFlagSet("signtx", flag.ExitOnError)
+	signTxIn := signTxCommand.String("in", "", "File holding the transaction to sign")
+	signTxOut := signTxCommand.String("out", "", "File to write the signed transaction to, defaults to -in")
+	signTxSigHash := signTxCommand.String("sighash", "ALL", "Parts of the transaction the signatures commit to")
+
+	joinTxCommand := flag.NewFlagSet("jointx", flag.ExitOnError)
+	joinTxIn := joinTxCommand.String("in", "", "Comma separated files holding the transactions to join")
+	joinTxOut := joinTxCommand.String("out", "", "File to write the joined transaction to")
+
+	broadcastTxCommand := flag.NewFlagSet("broadcasttx", flag.ExitOnError)
+	broadcastTxIn := broadcastTxCommand.String("in", "", "File holding the signed transaction")
 
 	printChainCommand := flag.NewFlagSet("print", flag.ExitOnError)
 
 	reIndexUTXOCommand := flag.NewFlagSet("reindexutxo", flag.ExitOnError)
 
 	createWalletCommand := flag.NewFlagSet("createwallet", flag.ExitOnError)
+	createWalletAccount := createWalletCommand.Uint("account", 0, "Account to derive the address in, for wallets with a seed phrase")
+	createWalletType := createWalletCommand.String("type", "", "Key type: p256 (default), secp256k1 or ed25519")
+
+	createHDWalletCommand := flag.NewFlagSet("createhdwallet", flag.ExitOnError)
+	createHDWalletWords := createHDWalletCommand.Int("words", 12, "Number of words of the seed phrase")
+	createHDWalletPassphrase := createHDWalletCommand.String("passphrase", "", "Optional passphrase protecting the seed phrase")
+	createHDWalletType := createHDWalletCommand.String("type", "p256", "Type of the derived keys: p256 or secp256k1")
+
+	restoreWalletCommand := flag.NewFlagSet("restorewallet", flag.ExitOnError)
+	restoreWalletMnemonic := restoreWalletCommand.String("mnemonic", "", "Seed phrase of the wallet")
+	restoreWalletPassphrase := restoreWalletCommand.String("passphrase", "", "Passphrase the seed phrase was created with")
+	restoreWalletGapLimit := restoreWalletCommand.Int("gaplimit", wallet.DefaultGapLimit, "Unused addresses in a row after which the scan stops")
+	restoreWalletType := restoreWalletCommand.String("type", "p256", "Type of the derived keys: p256 or secp256k1")
+
+	showMnemonicCommand := flag.NewFlagSet("showmnemonic", flag.ExitOnError)
+
+	dumpPrivKeyCommand := flag.NewFlagSet("dumpprivkey", flag.ExitOnError)
+	dumpPrivKeyAddress := dumpPrivKeyCommand.String("address", "", "Address of the key")
+
+	importPrivKeyCommand := flag.NewFlagSet("importprivkey", flag.ExitOnError)
+	importPrivKeyKey := importPrivKeyCommand.String("key", "", "Private key printed by dumpprivkey")
+	importPrivKeyRescan := importPrivKeyCommand.Bool("rescan", true, "Look up the outputs of the address on the chain")
+
+	importAddressCommand := flag.NewFlagSet("importaddress", flag.ExitOnError)
+	importAddressAddress := importAddressCommand.String("address", "", "Address to watch")
+	importAddressRescan := importAddressCommand.Bool("rescan", true, "Look up the outputs of the address on the chain")
+
+	importPubKeyCommand := flag.NewFlagSet("importpubkey", flag.ExitOnError)
+	importPubKeyKey := importPubKeyCommand.String("pubkey", "", "Hex encoded public key to watch")
+	importPubKeyRescan := importPubKeyCommand.Bool("rescan", true, "Look up the outputs of the address on the chain")
+
+	listTransactionsCommand := flag.NewFlagSet("listtransactions", flag.ExitOnError)
+	listTransactionsCount := listTransactionsCommand.Int("count", 0, "Number of most recent transactions to list, all when 0")
+	listTransactionsAddress := listTransactionsCommand.String("address", "", "Only list the transactions of this address")
+
+	setLabelCommand := flag.NewFlagSet("setlabel", flag.ExitOnError)
+	setLabelAddress := setLabelCommand.String("address", "", "Address to label")
+	setLabelLabel := setLabelCommand.String("label", "", "Label of the address")
+
+	setMemoCommand := flag.NewFlagSet("setmemo", flag.ExitOnError)
+	setMemoTx := setMemoCommand.String("tx", "", "Hex id of the transaction")
+	setMemoMemo := setMemoCommand.String("memo", "", "Note to attach")
+
+	getAddressInfoCommand := flag.NewFlagSet("getaddressinfo", flag.ExitOnError)
+	getAddressInfoAddress := getAddressInfoCommand.String("address", "", "Address to describe")
+
+	listUnspentCommand := flag.NewFlagSet("listunspent", flag.ExitOnError)
+	listUnspentAddress := listUnspentCommand.String("address", "", "Only list the outputs of this address")
+
+	lockUnspentCommand := flag.NewFlagSet("lockunspent", flag.ExitOnError)
+	lockUnspentOutpoints := lockUnspentCommand.String("outpoints", "", "Comma separated TXID:INDEX outputs")
+	lockUnspentUnlock := lockUnspentCommand.Bool("unlock", false, "Unlock the outputs instead")
+
+	rescanCommand := flag.NewFlagSet("rescan", flag.ExitOnError)
+	rescanFrom := rescanCommand.Int("from", 0, "Height of the first block to scan")
+
+	encryptWalletCommand := flag.NewFlagSet("encryptwallet", flag.ExitOnError)
+
+	changePassphraseCommand := flag.NewFlagSet("changepassphrase", flag.ExitOnError)
+
+	unlockCommand := flag.NewFlagSet("unlock", flag.ExitOnError)
+	unlockTimeout := unlockCommand.Int("timeout", 300, "Seconds the wallet stays unlocked")
+
+	lockCommand := flag.NewFlagSet("lock", flag.ExitOnError)
 
 	listAddressesCommand := flag.NewFlagSet("listaddresses", flag.ExitOnError)
+	listAddressesPubKeys := listAddressesCommand.Bool("pubkeys", false, "Print the public keys as well")
+
+	setCoinSelectionCommand := flag.NewFlagSet("setcoinselection", flag.ExitOnError)
+	setCoinSelectionStrategy := setCoinSelectionCommand.String("strategy", "", "largest, smallest, bnb or random")
+
+	createMultiSigCommand := flag.NewFlagSet("createmultisig", flag.ExitOnError)
+	createMultiSigRequired := createMultiSigCommand.Int("required", 0, "Number of signatures required to spend")
+	createMultiSigKeys := createMultiSigCommand.String("keys", "", "Comma separated public keys or addresses of this node")
+
+	signPartialCommand := flag.NewFlagSet("signpartial", flag.ExitOnError)
+	signPartialFrom := signPartialCommand.String("from", "", "Source multisig address")
+	signPartialTo := signPartialCommand.String("to", "", "Destination wallet address")
+	signPartialAmount := signPartialCommand.Int("amount", 0, "Transfer amount")
+	signPartialTx := signPartialCommand.String("tx", "", "Hex encoded partially signed transaction")
+
+	combineSigsCommand := flag.NewFlagSet("combinesigs", flag.ExitOnError)
+	combineSigsTxs := combineSigsCommand.String("txs", "", "Comma separated hex encoded partially signed transactions")
 
 	startNodeCommand := flag.NewFlagSet("startnode", flag.ExitOnError)
 	startNodeData := startNodeCommand.String("miner", "", "Enables mining and requires an address for the rewards")
+	startNodeAssumeValid := startNodeCommand.Bool("assumevalid", true, "Skip the scripts of the blocks proven to lead to the last checkpoint past the genesis")
+
+	generateCommand := flag.NewFlagSet("generate", flag.ExitOnError)
+	generateBlocks := generateCommand.Int("blocks", 1, "Number of blocks to mine")
+	generateTo := generateCommand.String("to", "", "Address receiving the coinbase of every block")
 
-	switch os.Args[1] {
+	switch args[0] {
 	case "createchain":
-		err := createChainCommand.Parse(os.Args[2:])
+		err := createChainCommand.Parse(args[1:])
 		blockchain.Handle(err)
 
 	case "balance":
-		err := balanceCommand.Parse(os.Args[2:])
+		err := balanceCommand.Parse(args[1:])
 		blockchain.Handle(err)
 
 	case "send":
-		err := sendCommand.Parse(os.Args[2:])
+		err := sendCommand.Parse(args[1:])
+		blockchain.Handle(err)
+
+	case "sendmany":
+		err := sendManyCommand.Parse(args[1:])
+		blockchain.Handle(err)
+
+	case "sendrawtx":
+		err := sendRawTxCommand.Parse(args[1:])
+		blockchain.Handle(err)
+
+	case "createtx":
+		err := createTxCommand.Parse(args[1:])
+		blockchain.Handle(err)
+
+	case "signtx":
+		err := signTxCommand.Parse(args[1:])
+		blockchain.Handle(err)
+
+	case "jointx":
+		err := joinTxCommand.Parse(args[1:])
+		blockchain.Handle(err)
+
+	case "broadcasttx":
+		err := broadcastTxCommand.Parse(args[1:])
 		blockchain.Handle(err)
 
 	case "print":
-		err := printChainCommand.Parse(os.Args[2:])
+		err := printChainCommand.Parse(args[1:])
 		blockchain.Handle(err)
 
 	case "listaddresses":
-		err := listAddressesCommand.Parse(os.Args[2:])
+		err := listAddressesCommand.Parse(args[1:])
 		wallet.Handle(err)
 
 	case "createwallet":
-		err := createWalletCommand.Parse(os.Args[2:])
+		err := createWalletCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "createhdwallet":
+		err := createHDWalletCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "restorewallet":
+		err := restoreWalletCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "showmnemonic":
+		err := showMnemonicCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "dumpprivkey":
+		err := dumpPrivKeyCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "importprivkey":
+		err := importPrivKeyCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "importaddress":
+		err := importAddressCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "importpubkey":
+		err := importPubKeyCommand.Parse(args[1:])
 		wallet.Handle(err)
+	case "listtransactions":
+		err := listTransactionsCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "setlabel":
+		err := setLabelCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "setmemo":
+		err := setMemoCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "getaddressinfo":
+		err := getAddressInfoCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "listunspent":
+		err := listUnspentCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "lockunspent":
+		err := lockUnspentCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "rescan":
+		err := rescanCommand.Parse(args[1:])
+		blockchain.Handle(err)
+	case "encryptwallet":
+		err := encryptWalletCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "changepassphrase":
+		err := changePassphraseCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "unlock":
+		err := unlockCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "lock":
+		err := lockCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "setcoinselection":
+		err := setCoinSelectionCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "createmultisig":
+		err := createMultiSigCommand.Parse(args[1:])
+		wallet.Handle(err)
+	case "signpartial":
+		err := signPartialCommand.Parse(args[1:])
+		blockchain.Handle(err)
+	case "combinesigs":
+		err := combineSigsCommand.Parse(args[1:])
+		blockchain.Handle(err)
 	case "reindexutxo":
-		err := reIndexUTXOCommand.Parse(os.Args[2:])
+		err := reIndexUTXOCommand.Parse(args[1:])
 		blockchain.Handle(err)
 	case "startnode":
-		err := startNodeCommand.Parse(os.Args[2:])
+		err := startNodeCommand.Parse(args[1:])
+		network.Handle(err)
+
+	case "generate":
+		err := generateCommand.Parse(args[1:])
 		network.Handle(err)
 
 	default:
@@ -220,27 +676,85 @@ func (cli *CommandLine) Run() {
 	}
 
 	if createChainCommand.Parsed() {
-		if *createChainData == "" {
+		if *createChainData == "" || *createChainName == "" {
 			createChainCommand.Usage()
 			runtime.Goexit()
 		}
-		cli.createChain(*createChainData, nodeID)
+		cli.createChain(*createChainData, *createChainName, *createChainOut, nodeID)
 	}
 
 	if balanceCommand.Parsed() {
 		if *balanceData == "" {
-			balanceCommand.Usage()
-			runtime.Goexit()
+			cli.getWalletBalance(nodeID)
+		} else {
+			cli.getBalance(*balanceData, nodeID)
 		}
-		cli.getBalance(*balanceData, nodeID)
 	}
 
 	if sendCommand.Parsed() {
-		if *sendAmount == 0 && *sendFrom == "" && *sendTo == "" {
+		if *sendAmount <= 0 || *sendTo == "" {
 			sendCommand.Usage()
 			runtime.Goexit()
 		}
-		cli.send(*sendFrom, *sendTo, *sendAmount, nodeID)
+		var from []string
+		if *sendFrom != "" {
+			from = strings.Split(*sendFrom, ",")
+		}
+		opts := sendOptions{*sendChange, *sendLockTime, *sendCoins, *sendDryRun, *sendMemo}
+		cli.send(from, *sendTo, *sendAmount, opts, nodeID)
+	}
+
+	if sendManyCommand.Parsed() {
+		if (*sendManyTo == "") == (*sendManyFile == "") {
+			sendManyCommand.Usage()
+			runtime.Goexit()
+		}
+		var from []string
+		if *sendManyFrom != "" {
+			from = strings.Split(*sendManyFrom, ",")
+		}
+		opts := sendOptions{*sendManyChange, *sendManyLockTime, *sendManyCoins, *sendManyDryRun, *sendManyMemo}
+		cli.sendMany(from, *sendManyTo, *sendManyFile, opts, nodeID)
+	}
+
+	if sendRawTxCommand.Parsed() {
+		if *sendRawTxData == "" {
+			sendRawTxCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.sendRawTx(*sendRawTxData)
+	}
+
+	if createTxCommand.Parsed() {
+		if *createTxFrom == "" || *createTxTo == "" || *createTxAmount <= 0 || *createTxOut == "" {
+			createTxCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.createTx(*createTxFrom, *createTxTo, *createTxAmount, *createTxLockTime, *createTxOut, nodeID)
+	}
+
+	if signTxCommand.Parsed() {
+		if *signTxIn == "" {
+			signTxCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.signTx(*signTxIn, *signTxOut, *signTxSigHash, nodeID)
+	}
+
+	if joinTxCommand.Parsed() {
+		if *joinTxIn == "" || *joinTxOut == "" {
+			joinTxCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.joinTx(*joinTxIn, *joinTxOut)
+	}
+
+	if broadcastTxCommand.Parsed() {
+		if *broadcastTxIn == "" {
+			broadcastTxCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.broadcastTx(*broadcastTxIn)
 	}
 
 	if printChainCommand.Parsed() {
@@ -248,11 +762,159 @@ func (cli *CommandLine) Run() {
 	}
 
 	if createWalletCommand.Parsed() {
-		cli.createWallet(nodeID)
+		cli.createWallet(*createWalletAccount, *createWalletType, nodeID)
+	}
+
+	if createHDWalletCommand.Parsed() {
+		cli.createHDWallet(*createHDWalletWords, *createHDWalletPassphrase, *createHDWalletType, nodeID)
+	}
+
+	if restoreWalletCommand.Parsed() {
+		if *restoreWalletMnemonic == "" || *restoreWalletGapLimit <= 0 {
+			restoreWalletCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.restoreWallet(*restoreWalletMnemonic, *restoreWalletPassphrase, *restoreWalletGapLimit, *restoreWalletType, nodeID)
+	}
+
+	if showMnemonicCommand.Parsed() {
+		cli.showMnemonic(nodeID)
 	}
 
 	if listAddressesCommand.Parsed() {
-		cli.listAddresses(nodeID)
+		cli.listAddresses(*listAddressesPubKeys, nodeID)
+	}
+
+	if dumpPrivKeyCommand.Parsed() {
+		if *dumpPrivKeyAddress == "" {
+			dumpPrivKeyCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.dumpPrivKey(*dumpPrivKeyAddress, nodeID)
+	}
+
+	if importPrivKeyCommand.Parsed() {
+		if *importPrivKeyKey == "" {
+			importPrivKeyCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.importPrivKey(*importPrivKeyKey, *importPrivKeyRescan, nodeID)
+	}
+
+	if importAddressCommand.Parsed() {
+		if *importAddressAddress == "" {
+			importAddressCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.importAddress(*importAddressAddress, *importAddressRescan, nodeID)
+	}
+
+	if importPubKeyCommand.Parsed() {
+		if *importPubKeyKey == "" {
+			importPubKeyCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.importPubKey(*importPubKeyKey, *importPubKeyRescan, nodeID)
+	}
+
+	if listTransactionsCommand.Parsed() {
+		cli.listTransactions(*listTransactionsCount, *listTransactionsAddress, nodeID)
+	}
+
+	if setLabelCommand.Parsed() {
+		if *setLabelAddress == "" {
+			setLabelCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.setLabel(*setLabelAddress, *setLabelLabel, nodeID)
+	}
+
+	if setMemoCommand.Parsed() {
+		if *setMemoTx == "" {
+			setMemoCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.setMemo(*setMemoTx, *setMemoMemo, nodeID)
+	}
+
+	if getAddressInfoCommand.Parsed() {
+		if *getAddressInfoAddress == "" {
+			getAddressInfoCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.getAddressInfo(*getAddressInfoAddress, nodeID)
+	}
+
+	if listUnspentCommand.Parsed() {
+		cli.listUnspent(*listUnspentAddress, nodeID)
+	}
+
+	if lockUnspentCommand.Parsed() {
+		if *lockUnspentOutpoints == "" {
+			lockUnspentCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.lockUnspent(*lockUnspentOutpoints, *lockUnspentUnlock, nodeID)
+	}
+
+	if rescanCommand.Parsed() {
+		if *rescanFrom < 0 {
+			rescanCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.rescan(*rescanFrom, nodeID)
+	}
+
+	if encryptWalletCommand.Parsed() {
+		cli.encryptWallet(nodeID)
+	}
+
+	if changePassphraseCommand.Parsed() {
+		cli.changePassphrase(nodeID)
+	}
+
+	if unlockCommand.Parsed() {
+		if *unlockTimeout <= 0 {
+			unlockCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.unlockWallet(*unlockTimeout, nodeID)
+	}
+
+	if lockCommand.Parsed() {
+		cli.lockWallet(nodeID)
+	}
+
+	if setCoinSelectionCommand.Parsed() {
+		if *setCoinSelectionStrategy == "" {
+			setCoinSelectionCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.setCoinSelection(*setCoinSelectionStrategy, nodeID)
+	}
+
+	if createMultiSigCommand.Parsed() {
+		if *createMultiSigRequired == 0 || *createMultiSigKeys == "" {
+			createMultiSigCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.createMultiSig(*createMultiSigRequired, *createMultiSigKeys, nodeID)
+	}
+
+	if signPartialCommand.Parsed() {
+		if *signPartialTx == "" && (*signPartialFrom == "" || *signPartialTo == "" || *signPartialAmount <= 0) {
+			signPartialCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.signPartial(*signPartialFrom, *signPartialTo, *signPartialAmount, *signPartialTx, nodeID)
+	}
+
+	if combineSigsCommand.Parsed() {
+		if *combineSigsTxs == "" {
+			combineSigsCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.combineSigs(*combineSigsTxs, nodeID)
 	}
 
 	if reIndexUTXOCommand.Parsed() {
@@ -260,7 +922,16 @@ func (cli *CommandLine) Run() {
 	}
 
 	if startNodeCommand.Parsed() {
+		blockchain.SkipCheckpointedScripts = *startNodeAssumeValid
 		cli.startNode(nodeID, *startNodeData)
 	}
 
+	if generateCommand.Parsed() {
+		if *generateTo == "" || *generateBlocks < 1 {
+			generateCommand.Usage()
+			runtime.Goexit()
+		}
+		cli.generate(*generateBlocks, *generateTo, nodeID)
+	}
+
 }
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	"gitlab.com/thesepehrm/first-blockchain/blockchain"
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+	"gitlab.com/thesepehrm/first-blockchain/network"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+// generate mines blocks at once. A running node of nodeID mines them with its
+// memory pool, otherwise they are mined here with their coinbase only
+func (cli *CommandLine) generate(blocks int, address, nodeID string) {
+	if !chaincfg.Active.GenerateSupported {
+		log.Panicf("Blocks can not be generated on %s", chaincfg.Active.Name)
+	}
+	if !wallet.ValidateAddress(address) {
+		log.Panic("Address is not valid")
+	}
+
+	nodeAddress := fmt.Sprintf("localhost:%s", nodeID)
+	if network.SendGenerate(nodeAddress, blocks, address) {
+		fmt.Printf("Node %s is generating %d blocks\n", nodeAddress, blocks)
+		return
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+	UTXOSet := blockchain.UTXOSet{Blockchain: chain}
+
+	for i := 0; i < blocks; i++ {
+		block := chain.MineBlock([]*blockchain.Transaction{blockchain.CoinbaseTx(address, "")})
+		err := UTXOSet.Update(block)
+		blockchain.Handle(err)
+		fmt.Printf("Generated block %x\n", block.Hash)
+	}
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+
+	"gitlab.com/thesepehrm/first-blockchain/blockchain"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+func (cli *CommandLine) createHDWallet(words int, passphrase string, keyTypeName string, nodeID string) {
+	defer holdWallets(nodeID)()
+
+	w := openWallets(nodeID)
+	if w.HD != nil {
+		log.Panic("Wallet already has a seed phrase")
+	}
+
+	keyType, err := wallet.ParseKeyType(keyTypeName)
+	wallet.Handle(err)
+
+	mnemonic, err := wallet.NewMnemonic(words)
+	wallet.Handle(err)
+
+	w.HD, err = wallet.NewHDChain(mnemonic, passphrase, keyType)
+	wallet.Handle(err)
+
+	address, err := w.AddHDWallet(0, wallet.ReceiveBranch)
+	wallet.Handle(err)
+	w.SaveFile(nodeID)
+
+	fmt.Println("Write down the seed phrase, it is all you need to restore the wallet:")
+	fmt.Println(mnemonic)
+	fmt.Printf("New wallet address is: %s\n", address)
+}
+
+func (cli *CommandLine) restoreWallet(mnemonic, passphrase string, gapLimit int, keyTypeName string, nodeID string) {
+	defer holdWallets(nodeID)()
+
+	w := openWallets(nodeID)
+	if w.HD != nil {
+		log.Panic("Wallet already has a seed phrase")
+	}
+
+	keyType, err := wallet.ParseKeyType(keyTypeName)
+	wallet.Handle(err)
+
+	w.HD, err = wallet.NewHDChain(mnemonic, passphrase, keyType)
+	wallet.Handle(err)
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+	used := chain.UsedPubKeyHashes()
+
+	found, err := w.Discover(gapLimit, func(pubKeyHash []byte) bool {
+		return used[hex.EncodeToString(pubKeyHash)]
+	})
+	wallet.Handle(err)
+
+	// Bring back the history of the addresses found
+	_, err = rescanWallet(chain, w, 0)
+	blockchain.Handle(err)
+
+	// A fresh receive address is ready even if nothing was found
+	address, err := w.AddHDWallet(0, wallet.ReceiveBranch)
+	wallet.Handle(err)
+	w.SaveFile(nodeID)
+
+	fmt.Printf("Restored %d used addresses, next address is: %s\n", found, address)
+}
+
+func (cli *CommandLine) showMnemonic(nodeID string) {
+	w, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	if w.HD == nil {
+		log.Panic("Wallet has no seed phrase")
+	}
+	fmt.Println(w.HD.Mnemonic)
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab.com/thesepehrm/first-blockchain/blockchain"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+func (cli *CommandLine) listTransactions(count int, address string, nodeID string) {
+	defer holdWallets(nodeID)()
+
+	w, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	chain.SyncWallet(w)
+	w.SaveFile(nodeID)
+	bestHeight := chain.GetBestHeight()
+
+	var records []*wallet.TxRecord
+	for _, rec := range w.ListTransactions() {
+		if address == "" || containsString(rec.Addresses, address) {
+			records = append(records, rec)
+		}
+	}
+	if count > 0 && len(records) > count {
+		records = records[len(records)-count:]
+	}
+
+	for _, rec := range records {
+		kind := "send"
+		switch {
+		case rec.Dropped:
+			kind = "dropped"
+		case rec.Coinbase:
+			kind = "mined"
+		case rec.Sent == 0:
+			kind = "receive"
+		case rec.Received == rec.Sent:
+			kind = "self"
+		}
+
+		var addresses []string
+		for _, address := range rec.Addresses {
+			if label := w.Label(address); label != "" {
+				address += " (" + label + ")"
+			}
+			addresses = append(addresses, address)
+		}
+
+		fmt.Printf("%s %x %-7s %+d %d confirmations %s\n",
+			time.Unix(rec.Timestamp, 0).Format("2006-01-02 15:04:05"), rec.ID, kind, rec.Net(),
+			rec.Confirmations(bestHeight), strings.Join(addresses, ", "))
+		if rec.Memo != "" {
+			fmt.Printf("    memo: %s\n", rec.Memo)
+		}
+	}
+}
+
+func (cli *CommandLine) setLabel(address, label string, nodeID string) {
+	defer holdWallets(nodeID)()
+
+	w, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	err = w.SetLabel(address, label)
+	wallet.Handle(err)
+	w.SaveFile(nodeID)
+
+	fmt.Printf("Label of %s set to %q\n", address, label)
+}
+
+func (cli *CommandLine) setMemo(txID, memo string, nodeID string) {
+	defer holdWallets(nodeID)()
+
+	w, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	err = w.SetMemo(txID, memo)
+	wallet.Handle(err)
+	w.SaveFile(nodeID)
+
+	fmt.Printf("Memo of transaction #%s set to %q\n", txID, memo)
+}
+
+func (cli *CommandLine) getAddressInfo(address string, nodeID string) {
+	if !wallet.ValidateAddress(address) {
+		wallet.Handle(fmt.Errorf("address %s is not valid", address))
+	}
+
+	w, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	fmt.Printf("Address: %s\n", address)
+
+	if key, ok := w.Wallets[address]; ok {
+		fmt.Println("Type: key")
+		fmt.Printf("Key type: %s\n", key.PrivateKey.Type)
+		fmt.Printf("Public key: %x\n", key.PublicKey)
+		if key.Path != "" {
+			fmt.Printf("Path: %s\n", key.Path)
+		}
+	} else if multiSig, ok := w.GetMultiSig(address); ok {
+		fmt.Printf("Type: %d of %d multisig\n", multiSig.Required, len(multiSig.PublicKeys))
+		fmt.Printf("Redeem script: %s\n", hex.EncodeToString(multiSig.RedeemScript))
+	} else if watchOnly, ok := w.GetWatchOnly(address); ok {
+		fmt.Println("Type: watch-only")
+		if watchOnly.PublicKey != nil {
+			fmt.Printf("Key type: %s\n", wallet.KeyType(watchOnly.PublicKey[0]))
+			fmt.Printf("Public key: %x\n", watchOnly.PublicKey)
+		}
+	} else {
+		fmt.Println("Type: external")
+	}
+
+	if label := w.Label(address); label != "" {
+		fmt.Printf("Label: %s\n", label)
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	UTXO := blockchain.UTXOSet{Blockchain: chain}
+	defer chain.Database.Close()
+
+	_, lockHash, err := wallet.DecodeAddress(address)
+	wallet.Handle(err)
+	balance := 0
+	unspent := UTXO.FindUnspentOutputs([][]byte{lockHash})
+	for _, out := range unspent {
+		balance += out.Output.Value
+	}
+	fmt.Printf("Balance: %d in %d outputs\n", balance, len(unspent))
+
+	transactions := 0
+	for _, rec := range w.Transactions {
+		if containsString(rec.Addresses, address) {
+			transactions++
+		}
+	}
+	fmt.Printf("Transactions: %d\n", transactions)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	"gitlab.com/thesepehrm/first-blockchain/blockchain"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+func (cli *CommandLine) dumpPrivKey(address string, nodeID string) {
+	w, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	key, ok := w.Wallets[address]
+	if _, watched := w.GetWatchOnly(address); !ok && watched {
+		log.Panicf("%s: %v", address, wallet.ErrWatchOnly)
+	}
+	if !ok {
+		log.Panicf("Address %s is not in the wallet", address)
+	}
+
+	fmt.Println(wallet.EncodePrivateKey(key.PrivateKey))
+}
+
+func (cli *CommandLine) importPrivKey(encoded string, rescan bool, nodeID string) {
+	defer holdWallets(nodeID)()
+
+	privateKey, err := wallet.DecodePrivateKey(encoded)
+	wallet.Handle(err)
+
+	w := openWallets(nodeID)
+	address := w.ImportWallet(privateKey)
+	w.SaveFile(nodeID)
+
+	fmt.Printf("Imported %s\n", address)
+
+	if rescan {
+		scanAddress(w, address, nodeID)
+	}
+}
+
+// scanAddress rescans the whole chain for a newly imported address and
+// reports its unspent outputs
+func scanAddress(w *wallet.Wallets, address string, nodeID string) {
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	_, err := rescanWallet(chain, w, 0)
+	w.SaveFile(nodeID)
+	blockchain.Handle(err)
+
+	count, balance := 0, 0
+	for _, rec := range w.ListUnspent() {
+		if rec.Address == address {
+			count++
+			balance += rec.Value
+		}
+	}
+	fmt.Printf("Found %d unspent outputs worth %d\n", count, balance)
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+
+	"gitlab.com/thesepehrm/first-blockchain/blockchain"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+func (cli *CommandLine) createMultiSig(required int, keys string, nodeID string) {
+	defer holdWallets(nodeID)()
+
+	wallets := openWallets(nodeID)
+
+	var publicKeys [][]byte
+	for _, key := range strings.Split(keys, ",") {
+		key = strings.TrimSpace(key)
+
+		// Addresses of our own wallets stand for their public keys
+		if w, ok := wallets.Wallets[key]; ok {
+			publicKeys = append(publicKeys, w.PublicKey)
+			continue
+		}
+
+		publicKey, err := hex.DecodeString(key)
+		if err != nil {
+			log.Panicf("%s is neither a public key nor an address of this node", key)
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+
+	multiSig, err := wallet.NewMultiSig(required, publicKeys)
+	wallet.Handle(err)
+
+	address := wallets.AddMultiSig(multiSig)
+	wallets.SaveFile(nodeID)
+
+	fmt.Printf("Multisig address (%d of %d): %s\n", required, len(publicKeys), address)
+	fmt.Printf("Redeem script: %x\n", multiSig.RedeemScript)
+}
+
+func (cli *CommandLine) signPartial(from, to string, amount int, rawTx string, nodeID string) {
+	wallets, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	UTXO := blockchain.UTXOSet{Blockchain: chain}
+	defer chain.Database.Close()
+
+	var tx *blockchain.Transaction
+	if rawTx != "" {
+		tx = decodeTransaction(rawTx)
+	} else {
+		if !wallet.ValidateAddress(from) {
+			log.Panic("Source Address is not valid")
+		}
+		if !wallet.ValidateAddress(to) {
+			log.Panic("Destination Address is not valid")
+		}
+		if _, ok := wallets.GetMultiSig(from); !ok {
+			log.Panic("Source Address is not a multisig account of this node, use createmultisig first")
+		}
+		tx = blockchain.NewMultiSigTransaction(from, to, amount, 0, &UTXO)
+	}
+
+	signed := 0
+	for _, multiSig := range wallets.MultiSigs {
+		for _, publicKey := range multiSig.PublicKeys {
+			if w, ok := wallets.FindByPublicKey(publicKey); ok {
+				chain.SignMultiSigTransaction(tx, w.PrivateKey, multiSig.RedeemScript)
+				signed++
+			}
+		}
+	}
+
+	if signed == 0 {
+		log.Panic("This node holds none of the keys of its multisig accounts")
+	}
+
+	printPartialTransaction(chain, tx)
+}
+
+func (cli *CommandLine) combineSigs(rawTxs string, nodeID string) {
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	parts := strings.Split(rawTxs, ",")
+	tx := decodeTransaction(parts[0])
+
+	for _, part := range parts[1:] {
+		other := decodeTransaction(part)
+		err := chain.CombineSignatures(tx, other)
+		blockchain.Handle(err)
+	}
+
+	printPartialTransaction(chain, tx)
+}
+
+func printPartialTransaction(chain *blockchain.BlockChain, tx *blockchain.Transaction) {
+	if chain.VerifyTransaction(tx) {
+		fmt.Printf("Transaction #%x is fully signed, broadcast it with sendrawtx:\n", tx.ID)
+	} else {
+		fmt.Printf("Transaction #%x needs more signatures, pass it on to the next co-signer:\n", tx.ID)
+	}
+	fmt.Println(hex.EncodeToString(tx.Serialize()))
+}
+
+func decodeTransaction(rawTx string) *blockchain.Transaction {
+	data, err := hex.DecodeString(strings.TrimSpace(rawTx))
+	blockchain.Handle(err)
+
+	tx := blockchain.DeserializeTransaction(data)
+	return &tx
+}
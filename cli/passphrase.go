@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// stdin is shared by every prompt so that piped passphrases are not lost to
+// buffering
+var stdin = bufio.NewReader(os.Stdin)
+
+// readPassphrase asks for a passphrase on the terminal without echoing it,
+// or reads a line when the input is not a terminal
+func readPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+
+	if terminal.IsTerminal(int(os.Stdin.Fd())) {
+		passphrase, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return string(passphrase), err
+	}
+
+	return readLine("")
+}
+
+// readLine asks a question and reads the answer
+func readLine(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readNewPassphrase asks for a new passphrase twice
+func readNewPassphrase() string {
+	passphrase, err := readPassphrase("New passphrase: ")
+	wallet.Handle(err)
+
+	repeated, err := readPassphrase("Repeat new passphrase: ")
+	wallet.Handle(err)
+
+	if passphrase != repeated {
+		wallet.Handle(errors.New("passphrases do not match"))
+	}
+	return passphrase
+}
+
+func (cli *CommandLine) encryptWallet(nodeID string) {
+	defer holdWallets(nodeID)()
+
+	w, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	if w.IsEncrypted() {
+		log.Panic("Wallet is already encrypted, use changepassphrase")
+	}
+
+	err = w.Encrypt(readNewPassphrase())
+	wallet.Handle(err)
+	w.SaveFile(nodeID)
+
+	fmt.Println("Wallet encrypted, keep the passphrase safe: the keys can not be recovered without it")
+}
+
+func (cli *CommandLine) changePassphrase(nodeID string) {
+	defer holdWallets(nodeID)()
+
+	w, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	err = w.ChangePassphrase(readNewPassphrase())
+	wallet.Handle(err)
+	w.SaveFile(nodeID)
+
+	// The node may hold the key of the old passphrase
+	err = wallet.Lock(nodeID)
+	wallet.Handle(err)
+
+	fmt.Println("Passphrase changed")
+}
+
+func (cli *CommandLine) unlockWallet(timeout int, nodeID string) {
+	w, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	err = w.Unlock(nodeID, time.Duration(timeout)*time.Second)
+	wallet.Handle(err)
+
+	fmt.Printf("Wallet unlocked for %d seconds by node %s\n", timeout, nodeID)
+}
+
+func (cli *CommandLine) lockWallet(nodeID string) {
+	err := wallet.Lock(nodeID)
+	wallet.Handle(err)
+
+	fmt.Println("Wallet locked")
+}
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/thesepehrm/first-blockchain/blockchain"
+	"gitlab.com/thesepehrm/first-blockchain/network"
+	"gitlab.com/thesepehrm/first-blockchain/script"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+type payment struct {
+	Address string
+	Amount  int
+}
+
+// sendOptions are the settings shared by the commands paying out of the wallet
+type sendOptions struct {
+	Change   string
+	LockTime int64
+	Coins    string // Coin selection strategy, the wallet preference when empty
+	DryRun   bool   // Only print the outputs that would be spent
+	Memo     string // Note kept with the transaction in the wallet history
+}
+
+func (cli *CommandLine) sendMany(from []string, to, file string, opts sendOptions, nodeID string) {
+	var payments []payment
+	var err error
+
+	if file != "" {
+		payments, err = readPayments(file)
+	} else {
+		payments, err = parsePayments(to)
+	}
+	blockchain.Handle(err)
+
+	cli.pay(from, payments, opts, nodeID)
+}
+
+// pay builds, signs and broadcasts a single transaction with one output per
+// payment, spending from the given addresses or the whole wallet
+func (cli *CommandLine) pay(from []string, payments []payment, opts sendOptions, nodeID string) {
+	// Held until the spent outputs are saved as pending, so that concurrent
+	// sends see each other's reservations
+	defer holdWallets(nodeID)()
+
+	wallets, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	// Without explicit sources the combined balance of the wallet is used
+	if len(from) == 0 {
+		from = wallets.GetAllAddresses()
+		sort.Strings(from)
+	}
+
+	for _, address := range from {
+		if !wallet.ValidateAddress(address) {
+			log.Panicf("Source Address %s is not valid", address)
+		}
+		if _, ok := wallets.GetWatchOnly(address); ok {
+			log.Panicf("Source Address %s: %v", address, wallet.ErrWatchOnly)
+		}
+	}
+	for _, p := range payments {
+		if !wallet.ValidateAddress(p.Address) {
+			log.Panicf("Destination Address %s is not valid", p.Address)
+		}
+	}
+	if opts.Change != "" && !wallet.ValidateAddress(opts.Change) {
+		log.Panic("Change Address is not valid")
+	}
+
+	coins := opts.Coins
+	if coins == "" {
+		coins = wallets.CoinSelection
+	}
+	selector, err := blockchain.NewCoinSelector(coins)
+	blockchain.Handle(err)
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	UTXO := blockchain.UTXOSet{Blockchain: chain}
+	defer chain.Database.Close()
+
+	// Deterministic wallets send change to a fresh address of their own
+	change := opts.Change
+	if change == "" && wallets.HD != nil && !opts.DryRun {
+		change, err = wallets.AddHDWallet(0, wallet.ChangeBranch)
+		wallet.Handle(err)
+	}
+
+	// Outputs spent by our pending transactions or locked are off limits
+	chain.SyncWallet(wallets)
+
+	builder := blockchain.NewTxBuilder(&UTXO, from)
+	builder.Change = change
+	builder.Exclude = wallets.ReservedOutpoints()
+	builder.LockTime = opts.LockTime
+	builder.Selector = selector
+	for _, p := range payments {
+		builder.AddOutput(p.Address, p.Amount)
+	}
+
+	tx, err := builder.Build()
+	blockchain.Handle(err)
+
+	if opts.DryRun {
+		printSelection(builder.Selected, tx)
+		return
+	}
+
+	err = chain.SignTransactionWithWallets(tx, wallets)
+	blockchain.Handle(err)
+
+	rec := chain.RecordWalletTransaction(wallets, wallets.LockHashes(), tx, -1, time.Now().Unix())
+	if rec && opts.Memo != "" {
+		err = wallets.SetMemo(hex.EncodeToString(tx.ID), opts.Memo)
+		wallet.Handle(err)
+	}
+
+	lockHashes := wallets.LockHashes()
+	var spent []wallet.OutputRecord
+	for _, unspent := range builder.Selected {
+		spent = append(spent, wallet.OutputRecord{
+			TxID:          unspent.TxID,
+			Index:         unspent.Index,
+			Value:         unspent.Output.Value,
+			LockingScript: unspent.Output.LockingScript,
+			Address:       lockHashes[hex.EncodeToString(unspent.Output.LockHash())],
+			Height:        unspent.Height,
+		})
+	}
+	wallets.MarkPending(spent, tx.ID, time.Now().Unix())
+	wallets.SaveFile(nodeID)
+
+	if !tx.IsFinal(chain.GetBestHeight()+1, time.Now().Unix()) {
+		fmt.Printf("Transaction #%s is locked until %d, broadcast it with sendrawtx afterwards:\n", hex.EncodeToString(tx.ID), opts.LockTime)
+		fmt.Println(hex.EncodeToString(tx.Serialize()))
+		return
+	}
+
+	//chain.MineBlock([]*blockchain.Transaction{tx})
+	network.SendTX(network.KnownNodes[0], tx)
+	fmt.Printf("Sent Transaction #%s paying %d recipients\n", hex.EncodeToString(tx.ID), len(payments))
+}
+
+// printSelection previews the outputs a transaction would spend and create
+func printSelection(selected []blockchain.UnspentOutput, tx *blockchain.Transaction) {
+	total := 0
+	fmt.Println("Spending:")
+	for _, unspent := range selected {
+		fmt.Printf("  %x:%d %d\n", unspent.TxID, unspent.Index, unspent.Output.Value)
+		total += unspent.Output.Value
+	}
+	fmt.Printf("  total %d in %d outputs\n", total, len(selected))
+
+	fmt.Println("Paying:")
+	for i, out := range tx.Outputs {
+		fmt.Printf("  %d: %d to %s\n", i, out.Value, script.Disassemble(out.LockingScript))
+	}
+}
+
+// parsePayments reads ADDRESS:AMOUNT pairs separated by commas
+func parsePayments(list string) ([]payment, error) {
+	var payments []payment
+
+	for _, entry := range strings.Split(list, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("payment %q is not ADDRESS:AMOUNT", entry)
+		}
+
+		p, err := newPayment(parts[0], parts[1])
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+
+	return payments, nil
+}
+
+// readPayments reads a JSON object mapping addresses to amounts, or a CSV
+// file of address,amount records
+func readPayments(path string) ([]payment, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var payments []payment
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		amounts := make(map[string]int)
+		if err := json.Unmarshal(content, &amounts); err != nil {
+			return nil, err
+		}
+		for address, amount := range amounts {
+			payments = append(payments, payment{address, amount})
+		}
+		// Keep the outputs in a stable order
+		sort.Slice(payments, func(i, j int) bool { return payments[i].Address < payments[j].Address })
+	} else {
+		reader := csv.NewReader(strings.NewReader(string(content)))
+		reader.FieldsPerRecord = 2
+		reader.TrimLeadingSpace = true
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for i, record := range records {
+			// An optional header line names the columns
+			if i == 0 && strings.EqualFold(strings.TrimSpace(record[1]), "amount") {
+				continue
+			}
+			p, err := newPayment(record[0], record[1])
+			if err != nil {
+				return nil, err
+			}
+			payments = append(payments, p)
+		}
+	}
+
+	if len(payments) == 0 {
+		return nil, fmt.Errorf("%s lists no payments", path)
+	}
+
+	return payments, nil
+}
+
+func newPayment(address, amount string) (payment, error) {
+	value, err := strconv.Atoi(strings.TrimSpace(amount))
+	if err != nil || value <= 0 {
+		return payment{}, fmt.Errorf("invalid amount %q for %s", amount, address)
+	}
+	return payment{strings.TrimSpace(address), value}, nil
+}
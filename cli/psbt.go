@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"gitlab.com/thesepehrm/first-blockchain/blockchain"
+	"gitlab.com/thesepehrm/first-blockchain/network"
+	"gitlab.com/thesepehrm/first-blockchain/script"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+func (cli *CommandLine) createTx(from, to string, amount int, lockTime int64, out string, nodeID string) {
+	if !wallet.ValidateAddress(from) {
+		log.Panic("Source Address is not valid")
+	}
+	if !wallet.ValidateAddress(to) {
+		log.Panic("Destination Address is not valid")
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	UTXO := blockchain.UTXOSet{Blockchain: chain}
+	defer chain.Database.Close()
+
+	tx := blockchain.NewUnsignedTransaction(from, to, amount, lockTime, &UTXO)
+	ptx, err := chain.NewPartialTransaction(tx)
+	blockchain.Handle(err)
+
+	// Whatever the node knows about the keys involved helps the signer
+	if wallets, err := wallet.CreateWallets(nodeID); err == nil {
+		for _, multiSig := range wallets.MultiSigs {
+			ptx.AddRedeemScript(multiSig.RedeemScript)
+		}
+		for _, w := range wallets.Wallets {
+			ptx.AddKeyHint(blockchain.KeyHint{PublicKey: w.PublicKey, Path: w.Path})
+		}
+		// Cold storage keys are only known by their public half
+		for _, watchOnly := range wallets.WatchOnly {
+			if watchOnly.PublicKey != nil {
+				ptx.AddKeyHint(blockchain.KeyHint{PublicKey: watchOnly.PublicKey})
+			}
+		}
+	}
+
+	writePartialTransaction(out, ptx)
+	fmt.Printf("Unsigned transaction #%x written to %s\n", tx.ID, out)
+}
+
+func (cli *CommandLine) signTx(in, out, sigHash string, nodeID string) {
+	hashType, err := blockchain.ParseSigHashType(sigHash)
+	blockchain.Handle(err)
+
+	ptx := readPartialTransaction(in)
+
+	wallets, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	if !confirmSigning(ptx, wallets) {
+		fmt.Println("Transaction not signed")
+		return
+	}
+
+	signed := 0
+	for _, w := range wallets.Wallets {
+		count, err := ptx.Sign(w.PrivateKey, w.PublicKey, hashType)
+		blockchain.Handle(err)
+		signed += count
+	}
+
+	if out == "" {
+		out = in
+	}
+	writePartialTransaction(out, ptx)
+
+	fmt.Printf("Added %d signatures to transaction #%x\n", signed, ptx.Tx.ID)
+	if _, err := ptx.Finalize(); err == nil {
+		fmt.Println("Transaction is fully signed, broadcast it with broadcasttx")
+	}
+}
+
+// confirmSigning shows what the transaction pays and asks before signing it,
+// the signer has no chain to check the transaction against. Signatures
+// commit to the input values the fee is computed from, misreported ones only
+// make them invalid
+func confirmSigning(ptx *blockchain.PartialTransaction, wallets *wallet.Wallets) bool {
+	ours := make(map[string]bool)
+	for _, w := range wallets.Wallets {
+		ours[hex.EncodeToString(wallet.PublicKeyHash(w.PublicKey))] = true
+	}
+
+	fmt.Printf("Transaction #%x\n", ptx.Tx.ID)
+	fmt.Println("Paying:")
+	for i, out := range ptx.Tx.Outputs {
+		note := ""
+		if ours[hex.EncodeToString(out.LockHash())] {
+			note = " (this wallet)"
+		}
+		fmt.Printf("  %d: %d to %s%s\n", i, out.Value, script.Disassemble(out.LockingScript), note)
+	}
+
+	fee := ptx.Fee()
+	if fee < 0 {
+		fmt.Printf("Outputs exceed the inputs by %d\n", -fee)
+		return false
+	}
+	fmt.Printf("Fee: %d\n", fee)
+
+	answer, err := readLine("Sign this transaction? [y/N] ")
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func (cli *CommandLine) joinTx(in, out string) {
+	var ptx *blockchain.PartialTransaction
+	for _, path := range strings.Split(in, ",") {
+		other := readPartialTransaction(strings.TrimSpace(path))
+		if ptx == nil {
+			ptx = other
+			continue
+		}
+		err := ptx.Join(other)
+		blockchain.Handle(err)
+	}
+
+	writePartialTransaction(out, ptx)
+	fmt.Printf("Joined transaction #%x with %d inputs written to %s\n", ptx.Tx.ID, len(ptx.Tx.Inputs), out)
+	if _, err := ptx.Finalize(); err == nil {
+		fmt.Println("Transaction is fully signed, broadcast it with broadcasttx")
+	}
+}
+
+func (cli *CommandLine) broadcastTx(in string) {
+	ptx := readPartialTransaction(in)
+
+	tx, err := ptx.Finalize()
+	blockchain.Handle(err)
+
+	network.SendTX(network.KnownNodes[0], tx)
+	fmt.Printf("Sent Transaction #%s\n", hex.EncodeToString(tx.ID))
+}
+
+func writePartialTransaction(path string, ptx *blockchain.PartialTransaction) {
+	content := hex.EncodeToString(ptx.Serialize()) + "\n"
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+	blockchain.Handle(err)
+}
+
+func readPartialTransaction(path string) *blockchain.PartialTransaction {
+	content, err := ioutil.ReadFile(path)
+	blockchain.Handle(err)
+
+	data, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	blockchain.Handle(err)
+
+	ptx, err := blockchain.DeserializePartialTransaction(data)
+	blockchain.Handle(err)
+
+	return ptx
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"gitlab.com/thesepehrm/first-blockchain/blockchain"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+// rescanProgressStep is the number of blocks between progress reports
+const rescanProgressStep = 100
+
+func (cli *CommandLine) rescan(from int, nodeID string) {
+	defer holdWallets(nodeID)()
+
+	w, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	recorded, err := rescanWallet(chain, w, from)
+	w.SaveFile(nodeID)
+
+	if err == blockchain.ErrRescanCancelled {
+		fmt.Printf("Rescan cancelled, resume it with rescan -from %d\n", w.SyncedHeight)
+		return
+	}
+	blockchain.Handle(err)
+
+	balance := 0
+	unspent := w.ListUnspent()
+	for _, rec := range unspent {
+		balance += rec.Value
+	}
+	fmt.Printf("Found %d transactions and %d unspent outputs worth %d\n", recorded, len(unspent), balance)
+}
+
+// rescanWallet rescans the chain for the wallet, reporting progress on
+// stderr and stopping at the next block on interrupt
+func rescanWallet(chain *blockchain.BlockChain, w *wallet.Wallets, from int) (int, error) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	cancel := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-interrupt:
+			close(cancel)
+		case <-done:
+		}
+	}()
+
+	progress := func(height, bestHeight int) {
+		if height%rescanProgressStep == 0 || height == bestHeight {
+			fmt.Fprintf(os.Stderr, "Scanned block %d of %d\n", height, bestHeight)
+		}
+	}
+
+	return chain.RescanWallet(w, from, progress, cancel)
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"gitlab.com/thesepehrm/first-blockchain/blockchain"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+func (cli *CommandLine) listUnspent(address string, nodeID string) {
+	defer holdWallets(nodeID)()
+
+	w, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	chain.SyncWallet(w)
+	w.SaveFile(nodeID)
+	bestHeight := chain.GetBestHeight()
+
+	for _, rec := range w.ListUnspent() {
+		if address != "" && rec.Address != address {
+			continue
+		}
+
+		owner := rec.Address
+		if label := w.Label(owner); label != "" {
+			owner += " (" + label + ")"
+		}
+		if _, ok := w.GetWatchOnly(rec.Address); ok {
+			owner += " watch-only"
+		}
+
+		state := rec.State.String()
+		if rec.State == wallet.OutputPendingSpend {
+			state += fmt.Sprintf(" by %x", rec.SpentBy)
+		}
+
+		fmt.Printf("%s %d %d confirmations %s %s\n", rec.Outpoint(), rec.Value, bestHeight-rec.Height+1, state, owner)
+	}
+}
+
+func (cli *CommandLine) lockUnspent(outpoints string, unlock bool, nodeID string) {
+	defer holdWallets(nodeID)()
+
+	w, err := wallet.CreateWallets(nodeID)
+	wallet.Handle(err)
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	chain.SyncWallet(w)
+
+	for _, outpoint := range strings.Split(outpoints, ",") {
+		err := w.LockUnspent(strings.TrimSpace(outpoint), !unlock)
+		wallet.Handle(err)
+	}
+	w.SaveFile(nodeID)
+
+	if unlock {
+		fmt.Println("Unlocked", outpoints)
+	} else {
+		fmt.Println("Locked", outpoints)
+	}
+}
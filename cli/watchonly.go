@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+func (cli *CommandLine) importAddress(address string, rescan bool, nodeID string) {
+	defer holdWallets(nodeID)()
+
+	w := openWallets(nodeID)
+
+	address, err := w.AddWatchOnly(address)
+	wallet.Handle(err)
+	w.SaveFile(nodeID)
+
+	fmt.Printf("Watching %s\n", address)
+
+	if rescan {
+		scanAddress(w, address, nodeID)
+	}
+}
+
+func (cli *CommandLine) importPubKey(publicKey string, rescan bool, nodeID string) {
+	defer holdWallets(nodeID)()
+
+	key, err := hex.DecodeString(publicKey)
+	wallet.Handle(err)
+
+	w := openWallets(nodeID)
+
+	address, err := w.AddWatchOnlyPublicKey(key)
+	wallet.Handle(err)
+	w.SaveFile(nodeID)
+
+	fmt.Printf("Watching %s\n", address)
+
+	if rescan {
+		scanAddress(w, address, nodeID)
+	}
+}
@@ -0,0 +1,73 @@
+package network
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"net"
+
+	"gitlab.com/thesepehrm/first-blockchain/blockchain"
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+// Generate asks a node to mine blocks at once, on networks supporting it
+type Generate struct {
+	AddrFrom string
+	Blocks   int
+	Address  string // Receives the coinbase of every block
+}
+
+// SendGenerate asks the node at addr to mine blocks paying address, false
+// when no node listens there
+func SendGenerate(addr string, blocks int, address string) bool {
+	conn, err := net.Dial(protocol, addr)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	data := Generate{nodeAddress, blocks, address}
+	_, err = io.Copy(conn, bytes.NewReader(BuildRequest("generate", data)))
+	Handle(err)
+
+	return true
+}
+
+func HandleGenerate(req []byte, chain *blockchain.BlockChain) {
+	var buffer bytes.Buffer
+	var payload Generate
+
+	buffer.Write(req[commandLength:])
+
+	decoder := gob.NewDecoder(&buffer)
+	err := decoder.Decode(&payload)
+	Handle(err)
+
+	if !chaincfg.Active.GenerateSupported {
+		fmt.Printf("Refused to generate blocks on %s\n", chaincfg.Active.Name)
+		return
+	}
+	if !wallet.ValidateAddress(payload.Address) {
+		fmt.Printf("Refused to generate blocks paying %q: invalid address\n", payload.Address)
+		return
+	}
+
+	for _, hash := range GenerateBlocks(chain, payload.Blocks, payload.Address) {
+		fmt.Printf("Generated block %x\n", hash)
+	}
+}
+
+// GenerateBlocks mines blocks one after the other with the transactions of
+// the memory pool, the first blocks take as many as they can
+func GenerateBlocks(chain *blockchain.BlockChain, blocks int, address string) [][]byte {
+	var hashes [][]byte
+
+	for i := 0; i < blocks; i++ {
+		block := mineBlock(chain, minableTransactions(chain), address)
+		hashes = append(hashes, block.Hash)
+	}
+
+	return hashes
+}
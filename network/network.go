@@ -4,15 +4,20 @@ import (
 	"bytes"
 	"encoding/gob"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"os"
 	"runtime"
+	"sync"
 	"syscall"
+	"time"
 
 	"gitlab.com/thesepehrm/first-blockchain/blockchain"
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
 	"gopkg.in/vrecan/death.v3"
 )
 
@@ -27,9 +32,11 @@ type nodes []string
 var (
 	nodeAddress     string
 	minerAddress    string
-	KnownNodes      = nodes{"localhost:3000"}
+	KnownNodes      = append(nodes{}, chaincfg.Active.Seeds...)
 	blocksInTransit = [][]byte{}
 	memoryPool      = make(map[string]blockchain.Transaction)
+	memoryPoolTimes = make(map[string]time.Time)
+	memoryPoolMutex sync.Mutex // Guards memoryPool and memoryPoolTimes
 )
 
 type Addr struct {
@@ -45,6 +52,16 @@ type GetBlocks struct {
 	AddrFrom string
 }
 
+type GetHeaders struct {
+	AddrFrom string
+}
+
+// Headers is the chain of a node, oldest first
+type Headers struct {
+	AddrFrom string
+	Headers  []blockchain.BlockHeader
+}
+
 type GetData struct {
 	AddrFrom string
 	Type     string
@@ -92,6 +109,13 @@ func HandleConnection(conn net.Conn, chain *blockchain.BlockChain) {
 	req, err := ioutil.ReadAll(conn)
 	Handle(err)
 
+	magic := chaincfg.Active.Magic
+	if len(req) < len(magic)+commandLength || !bytes.Equal(req[:len(magic)], magic[:]) {
+		fmt.Printf("Dropped a message of another network from %s\n", conn.RemoteAddr())
+		return
+	}
+	req = req[len(magic):]
+
 	command := BytesToCmd(req[:commandLength])
 	fmt.Printf("Requested command: %s\n", command)
 
@@ -104,12 +128,18 @@ func HandleConnection(conn net.Conn, chain *blockchain.BlockChain) {
 		HandleGetBlocks(req, chain)
 	case "getdata":
 		HandleGetData(req, chain)
+	case "getheaders":
+		HandleGetHeaders(req, chain)
+	case "headers":
+		HandleHeaders(req, chain)
 	case "inv":
 		HandleInv(req, chain)
 	case "tx":
 		HandleTx(req, chain)
 	case "version":
 		HandleVersion(req, chain)
+	case "generate":
+		HandleGenerate(req, chain)
 
 	default:
 		fmt.Println("Unknown command")
@@ -117,6 +147,11 @@ func HandleConnection(conn net.Conn, chain *blockchain.BlockChain) {
 
 }
 
+// UseNetwork starts the list of known nodes over from the seeds of a network
+func UseNetwork(params *chaincfg.Params) {
+	KnownNodes = append(nodes{}, params.Seeds...)
+}
+
 func Start(nodeID, minerWalletAddress string) {
 	nodeAddress = fmt.Sprintf("localhost:%s", nodeID)
 	minerAddress = minerWalletAddress
@@ -159,9 +194,14 @@ func SendData(addr string, data []byte) {
 }
 
 func BuildAndSendData(addr, command string, data interface{}) {
+	SendData(addr, BuildRequest(command, data))
+}
+
+// BuildRequest prefixes the encoded data with the network magic and command
+func BuildRequest(command string, data interface{}) []byte {
+	magic := chaincfg.Active.Magic
 	payload := GobEncode(data)
-	req := append(CmdToBytes(command), payload...)
-	SendData(addr, req)
+	return append(append(magic[:], CmdToBytes(command)...), payload...)
 }
 
 func SendAddr(addr string) {
@@ -196,6 +236,11 @@ func SendGetBlocks(addr string) {
 	BuildAndSendData(addr, "getblocks", data)
 }
 
+func SendGetHeaders(addr string) {
+	data := GetHeaders{nodeAddress}
+	BuildAndSendData(addr, "getheaders", data)
+}
+
 func RequestBlocks() {
 
 	for _, node := range KnownNodes {
@@ -236,9 +281,25 @@ func HandleBlock(req []byte, chain *blockchain.BlockChain) {
 
 	block := blockchain.Deserialize(payload.Block)
 
+	if err := chain.ValidateBlock(block); err != nil {
+		fmt.Printf("Rejected block %x: %s\n", block.Hash, err)
+		return
+	}
+
+	lastHash := chain.LastHash
 	chain.AddBlock(block)
 	fmt.Printf("New Block Received and added to chain: %x\n", block.Hash)
 
+	// Keep the UTXO set in step with the tip so the next block in transit is
+	// validated against the chain it extends
+	if bytes.Equal(block.PrevHash, lastHash) && bytes.Equal(chain.LastHash, block.Hash) {
+		UTXOSet := blockchain.UTXOSet{Blockchain: chain}
+		if err := UTXOSet.Update(block); err != nil {
+			fmt.Printf("Could not update the UTXO set with block %x: %s\n", block.Hash, err)
+			UTXOSet.ReIndex()
+		}
+	}
+
 	if len(blocksInTransit) > 0 {
 		blockHash := blocksInTransit[0]
 		SendGetData(payload.AddrFrom, "block", blockHash)
@@ -262,10 +323,53 @@ func HandleGetBlocks(req []byte, chain *blockchain.BlockChain) {
 	err := decoder.Decode(&payload)
 	Handle(err)
 
-	blocks := chain.GetBlockHashes()
+	// Oldest first, so that the receiver can validate every block against
+	// its parent
+	hashes := chain.GetBlockHashes()
+	blocks := make([][]byte, len(hashes))
+	for i, hash := range hashes {
+		blocks[len(hashes)-1-i] = hash
+	}
 	SendInv(payload.AddrFrom, "block", blocks)
 }
 
+func HandleGetHeaders(req []byte, chain *blockchain.BlockChain) {
+	var buffer bytes.Buffer
+	var payload GetHeaders
+
+	buffer.Write(req[commandLength:])
+
+	decoder := gob.NewDecoder(&buffer)
+	err := decoder.Decode(&payload)
+	Handle(err)
+
+	chainHeaders := chain.GetHeaders()
+	headers := make([]blockchain.BlockHeader, len(chainHeaders))
+	for i, header := range chainHeaders {
+		headers[len(chainHeaders)-1-i] = header
+	}
+	BuildAndSendData(payload.AddrFrom, "headers", Headers{nodeAddress, headers})
+}
+
+// HandleHeaders lets the blocks of a peer leading to the last checkpoint skip
+// their scripts
+func HandleHeaders(req []byte, chain *blockchain.BlockChain) {
+	var buffer bytes.Buffer
+	var payload Headers
+
+	buffer.Write(req[commandLength:])
+
+	decoder := gob.NewDecoder(&buffer)
+	err := decoder.Decode(&payload)
+	Handle(err)
+
+	if err := chain.AssumeValidHeaders(payload.Headers); err != nil {
+		fmt.Printf("Rejected headers from %s: %s\n", payload.AddrFrom, err)
+		return
+	}
+	fmt.Printf("Headers from %s lead to the last checkpoint\n", payload.AddrFrom)
+}
+
 func HandleGetData(req []byte, chain *blockchain.BlockChain) {
 	var buffer bytes.Buffer
 	var payload GetData
@@ -283,8 +387,10 @@ func HandleGetData(req []byte, chain *blockchain.BlockChain) {
 
 		SendBlock(payload.AddrFrom, block)
 	case "tx":
-		txID := hex.EncodeToString(payload.ID)
-		tx := memoryPool[txID]
+		tx, ok := memoryPoolTx(hex.EncodeToString(payload.ID))
+		if !ok {
+			return
+		}
 
 		SendTX(payload.AddrFrom, &tx)
 	}
@@ -307,6 +413,10 @@ func HandleVersion(req []byte, chain *blockchain.BlockChain) {
 	if bestHeight > sentHeight {
 		SendVersion(payload.AddrFrom, chain)
 	} else if bestHeight < sentHeight {
+		// Blocks arriving before the headers have their scripts verified
+		if chain.SyncingToCheckpoint() {
+			SendGetHeaders(payload.AddrFrom)
+		}
 		SendGetBlocks(payload.AddrFrom)
 	}
 
@@ -328,7 +438,11 @@ func HandleTx(req []byte, chain *blockchain.BlockChain) {
 
 	txData := payload.Transaction
 	tx := blockchain.DeserializeTransaction(txData)
-	memoryPool[hex.EncodeToString(tx.ID)] = tx
+
+	if err := acceptToMemoryPool(chain, &tx); err != nil {
+		fmt.Printf("Rejected transaction %x: %s\n", tx.ID, err)
+		return
+	}
 
 	if nodeAddress == KnownNodes[0] { // Main full node
 		for _, node := range KnownNodes {
@@ -338,30 +452,173 @@ func HandleTx(req []byte, chain *blockchain.BlockChain) {
 
 		}
 	} else {
-		if len(memoryPool) >= 1 && len(minerAddress) > 0 {
+		if memoryPoolSize() >= 1 && len(minerAddress) > 0 {
 			MineTx(chain)
 		}
 	}
 
 }
 
-func MineTx(chain *blockchain.BlockChain) {
-	var txs []*blockchain.Transaction
+// acceptToMemoryPool adds tx to the memory pool when it can be mined in the
+// next block and spends none of the outputs the pool already spends
+func acceptToMemoryPool(chain *blockchain.BlockChain, tx *blockchain.Transaction) error {
+	if tx.IsCoinbase() {
+		return errors.New("coinbase transactions are only valid in blocks")
+	}
+	if !bytes.Equal(tx.ID, tx.Hash()) {
+		return errors.New("the transaction does not hash to its id")
+	}
+
+	height := chain.GetBestHeight() + 1
+	now := time.Now().Unix()
+
+	if !tx.IsFinal(height, now) {
+		return fmt.Errorf("lock time %d has not passed", tx.LockTime)
+	}
+
+	memoryPoolMutex.Lock()
+	defer memoryPoolMutex.Unlock()
 
-	for id := range memoryPool {
+	txID := hex.EncodeToString(tx.ID)
+	if _, ok := memoryPool[txID]; ok {
+		return errors.New("already in the memory pool")
+	}
+
+	spent := make(map[string]bool)
+	for _, poolTx := range memoryPool {
+		for _, in := range poolTx.Inputs {
+			spent[wallet.Outpoint(in.ID, in.Out)] = true
+		}
+	}
+
+	UTXOSet := blockchain.UTXOSet{Blockchain: chain}
+	if _, err := UTXOSet.CheckInputs(tx, spent); err != nil {
+		return err
+	}
+
+	if !chain.VerifyTransaction(tx) {
+		return errors.New("inputs can not be unlocked")
+	}
+
+	if err := tx.CheckSequenceLocks(height, now, UTXOSet.PrevOutputs(tx)); err != nil {
+		return err
+	}
+
+	addToMemoryPool(*tx)
+	return nil
+}
+
+func AddToMemoryPool(tx blockchain.Transaction) {
+	memoryPoolMutex.Lock()
+	defer memoryPoolMutex.Unlock()
+
+	addToMemoryPool(tx)
+}
+
+func addToMemoryPool(tx blockchain.Transaction) {
+	txID := hex.EncodeToString(tx.ID)
+	if _, ok := memoryPool[txID]; !ok {
+		memoryPoolTimes[txID] = time.Now()
+	}
+	memoryPool[txID] = tx
+}
 
-		tx := memoryPool[id]
-		//if chain.VerifyTransaction(&tx) {
-		txs = append(txs, &tx)
-		//}
+func RemoveFromMemoryPool(txID string) {
+	memoryPoolMutex.Lock()
+	defer memoryPoolMutex.Unlock()
+
+	delete(memoryPool, txID)
+	delete(memoryPoolTimes, txID)
+}
+
+// PruneMemoryPool drops transactions that have waited longer than
+// blockchain.MemoryPoolExpiry without being mined
+func PruneMemoryPool() {
+	memoryPoolMutex.Lock()
+	defer memoryPoolMutex.Unlock()
+
+	for txID, added := range memoryPoolTimes {
+		if time.Since(added) > blockchain.MemoryPoolExpiry {
+			fmt.Printf("Transaction %s expired from the memory pool\n", txID)
+			delete(memoryPool, txID)
+			delete(memoryPoolTimes, txID)
+		}
 	}
+}
+
+// memoryPoolTx returns the transaction of the memory pool with the given hex
+// id
+func memoryPoolTx(txID string) (blockchain.Transaction, bool) {
+	memoryPoolMutex.Lock()
+	defer memoryPoolMutex.Unlock()
+
+	tx, ok := memoryPool[txID]
+	return tx, ok
+}
+
+func memoryPoolSize() int {
+	memoryPoolMutex.Lock()
+	defer memoryPoolMutex.Unlock()
+
+	return len(memoryPool)
+}
+
+func MineTx(chain *blockchain.BlockChain) {
+	txs := minableTransactions(chain)
 
 	if len(txs) == 0 {
 		fmt.Println("All Transactions are invalid")
 		return
 	}
 
-	cbTx := blockchain.CoinbaseTx(minerAddress, "")
+	mineBlock(chain, txs, minerAddress)
+
+	if memoryPoolSize() > 0 {
+		MineTx(chain)
+	}
+}
+
+// minableTransactions returns the transactions of the memory pool that can
+// be mined together in the next block
+func minableTransactions(chain *blockchain.BlockChain) []*blockchain.Transaction {
+	var txs []*blockchain.Transaction
+
+	PruneMemoryPool()
+
+	memoryPoolMutex.Lock()
+	pool := make([]blockchain.Transaction, 0, len(memoryPool))
+	for _, tx := range memoryPool {
+		pool = append(pool, tx)
+	}
+	memoryPoolMutex.Unlock()
+
+	height := chain.GetBestHeight() + 1
+	now := time.Now().Unix()
+
+	UTXOSet := blockchain.UTXOSet{Blockchain: chain}
+	spent := make(map[string]bool)
+
+	for i := range pool {
+		tx := &pool[i]
+		if !tx.IsFinal(height, now) || !chain.VerifyTransaction(tx) {
+			continue
+		}
+		if tx.CheckSequenceLocks(height, now, UTXOSet.PrevOutputs(tx)) != nil {
+			continue
+		}
+		// Last, it marks the outputs of tx as spent
+		if _, err := UTXOSet.CheckInputs(tx, spent); err != nil {
+			continue
+		}
+		txs = append(txs, tx)
+	}
+
+	return txs
+}
+
+// mineBlock mines txs with a coinbase paying address and announces the block
+func mineBlock(chain *blockchain.BlockChain, txs []*blockchain.Transaction, address string) *blockchain.Block {
+	cbTx := blockchain.CoinbaseTx(address, "")
 	txs = append(txs, cbTx)
 
 	newBlock := chain.MineBlock(txs)
@@ -372,7 +629,7 @@ func MineTx(chain *blockchain.BlockChain) {
 
 	for _, tx := range txs {
 		txID := hex.EncodeToString(tx.ID)
-		delete(memoryPool, txID)
+		RemoveFromMemoryPool(txID)
 	}
 
 	for _, node := range KnownNodes {
@@ -381,9 +638,7 @@ func MineTx(chain *blockchain.BlockChain) {
 		}
 	}
 
-	if len(memoryPool) > 0 {
-		MineTx(chain)
-	}
+	return newBlock
 }
 
 func HandleInv(request []byte, chain *blockchain.BlockChain) {
@@ -398,9 +653,18 @@ func HandleInv(request []byte, chain *blockchain.BlockChain) {
 	fmt.Printf("Recevied inventory with %d %s\n", len(payload.Items), payload.Type)
 
 	if payload.Type == "block" {
-		blocksInTransit = payload.Items
+		blocksInTransit = [][]byte{}
+		for _, blockHash := range payload.Items {
+			if _, err := chain.GetBlock(blockHash); err != nil {
+				blocksInTransit = append(blocksInTransit, blockHash)
+			}
+		}
 
-		blockHash := payload.Items[0]
+		if len(blocksInTransit) == 0 {
+			return
+		}
+
+		blockHash := blocksInTransit[0]
 		SendGetData(payload.AddrFrom, "block", blockHash)
 
 		newInTransit := [][]byte{}
@@ -415,7 +679,7 @@ func HandleInv(request []byte, chain *blockchain.BlockChain) {
 	if payload.Type == "tx" {
 		txID := payload.Items[0]
 
-		if memoryPool[hex.EncodeToString(txID)].ID == nil {
+		if _, ok := memoryPoolTx(hex.EncodeToString(txID)); !ok {
 			SendGetData(payload.AddrFrom, "tx", txID)
 		}
 	}
@@ -0,0 +1,54 @@
+package script
+
+// Builder assembles scripts, picking the shortest encoding for every push
+type Builder struct {
+	script []byte
+}
+
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+func (b *Builder) AddOp(opcode byte) *Builder {
+	b.script = append(b.script, opcode)
+	return b
+}
+
+func (b *Builder) AddData(data []byte) *Builder {
+	length := len(data)
+
+	switch {
+	case length == 0:
+		b.script = append(b.script, OP_0)
+		return b
+	case length < int(OP_PUSHDATA1):
+		b.script = append(b.script, byte(length))
+	case length <= 0xff:
+		b.script = append(b.script, OP_PUSHDATA1, byte(length))
+	case length <= 0xffff:
+		b.script = append(b.script, OP_PUSHDATA2, byte(length), byte(length>>8))
+	default:
+		b.script = append(b.script, OP_PUSHDATA4, byte(length), byte(length>>8), byte(length>>16), byte(length>>24))
+	}
+
+	b.script = append(b.script, data...)
+	return b
+}
+
+func (b *Builder) AddInt(n int64) *Builder {
+	switch {
+	case n == 0:
+		b.script = append(b.script, OP_0)
+	case n == -1:
+		b.script = append(b.script, OP_1NEGATE)
+	case n >= 1 && n <= 16:
+		b.script = append(b.script, OP_1+byte(n-1))
+	default:
+		b.AddData(encodeNumber(n))
+	}
+	return b
+}
+
+func (b *Builder) Script() []byte {
+	return append([]byte{}, b.script...)
+}
@@ -0,0 +1,485 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ripemd160"
+)
+
+const (
+	maxStackSize      = 1000
+	maxMultiSigKeys   = 20
+	maxNumberLength   = 4
+	maxLockTimeLength = 5 // Lock times may exceed the 32 bit range of regular numbers
+)
+
+var (
+	ErrVerifyFailed   = errors.New("script verification failed")
+	ErrEvalFalse      = errors.New("script evaluated to false")
+	ErrStackUnderflow = errors.New("not enough items on the stack")
+)
+
+// Checker gives the interpreter access to the transaction being verified
+type Checker interface {
+	// CheckSig verifies a signature of the input being spent, subScript is
+	// the script the signature commits to
+	CheckSig(signature, pubKey, subScript []byte) bool
+
+	// CheckLockTime reports whether the transaction lock time satisfies
+	// the lock time required by the script
+	CheckLockTime(lockTime int64) bool
+
+	// CheckSequence reports whether the input sequence satisfies the
+	// relative lock time required by the script
+	CheckSequence(sequence int64) bool
+}
+
+type stack [][]byte
+
+func (s *stack) push(item []byte) {
+	*s = append(*s, item)
+}
+
+func (s *stack) pop() ([]byte, error) {
+	if len(*s) == 0 {
+		return nil, ErrStackUnderflow
+	}
+	item := (*s)[len(*s)-1]
+	*s = (*s)[:len(*s)-1]
+	return item, nil
+}
+
+func (s *stack) peek() ([]byte, error) {
+	if len(*s) == 0 {
+		return nil, ErrStackUnderflow
+	}
+	return (*s)[len(*s)-1], nil
+}
+
+func (s *stack) popBool() (bool, error) {
+	item, err := s.pop()
+	if err != nil {
+		return false, err
+	}
+	return asBool(item), nil
+}
+
+func (s *stack) popInt() (int64, error) {
+	item, err := s.pop()
+	if err != nil {
+		return 0, err
+	}
+	return decodeNumber(item, maxNumberLength)
+}
+
+// Execute runs the unlocking script of an input followed by the locking
+// script of the output it spends, and returns nil if the output may be spent
+func Execute(unlockingScript, lockingScript []byte, checker Checker) error {
+	if !IsPushOnly(unlockingScript) {
+		return errors.New("unlocking script must only push data")
+	}
+
+	var st stack
+
+	if err := run(unlockingScript, &st, checker); err != nil {
+		return err
+	}
+
+	// Pay-to-script-hash runs the revealed redeem script on what is left
+	// once the hash of the script was matched
+	unlockingStack := append(stack{}, st...)
+
+	if err := run(lockingScript, &st, checker); err != nil {
+		return err
+	}
+
+	top, err := st.peek()
+	if err != nil || !asBool(top) {
+		return ErrEvalFalse
+	}
+
+	if !IsPayToScriptHash(lockingScript) {
+		return nil
+	}
+
+	redeemScript, err := unlockingStack.pop()
+	if err != nil {
+		return err
+	}
+	if err := run(redeemScript, &unlockingStack, checker); err != nil {
+		return err
+	}
+
+	top, err = unlockingStack.peek()
+	if err != nil || !asBool(top) {
+		return ErrEvalFalse
+	}
+
+	return nil
+}
+
+func run(script []byte, st *stack, checker Checker) error {
+	instructions, err := parse(script)
+	if err != nil {
+		return err
+	}
+
+	// One entry per open OP_IF, telling if its current branch is executed
+	var conditions []bool
+
+	for _, ins := range instructions {
+		executing := true
+		for _, condition := range conditions {
+			executing = executing && condition
+		}
+
+		switch ins.Opcode {
+		case OP_IF, OP_NOTIF:
+			branch := false
+			if executing {
+				if branch, err = st.popBool(); err != nil {
+					return err
+				}
+				if ins.Opcode == OP_NOTIF {
+					branch = !branch
+				}
+			}
+			conditions = append(conditions, branch)
+			continue
+		case OP_ELSE:
+			if len(conditions) == 0 {
+				return errors.New("OP_ELSE without OP_IF")
+			}
+			conditions[len(conditions)-1] = !conditions[len(conditions)-1]
+			continue
+		case OP_ENDIF:
+			if len(conditions) == 0 {
+				return errors.New("OP_ENDIF without OP_IF")
+			}
+			conditions = conditions[:len(conditions)-1]
+			continue
+		}
+
+		if !executing {
+			continue
+		}
+
+		if err := step(ins, script, st, checker); err != nil {
+			return err
+		}
+
+		if len(*st) > maxStackSize {
+			return errors.New("stack size limit exceeded")
+		}
+	}
+
+	if len(conditions) != 0 {
+		return errors.New("unbalanced conditional")
+	}
+
+	return nil
+}
+
+func step(ins instruction, script []byte, st *stack, checker Checker) error {
+	switch {
+	case ins.Opcode == OP_0:
+		st.push([]byte{})
+		return nil
+	case ins.Data != nil:
+		st.push(ins.Data)
+		return nil
+	case ins.Opcode == OP_1NEGATE:
+		st.push(encodeNumber(-1))
+		return nil
+	case ins.Opcode >= OP_1 && ins.Opcode <= OP_16:
+		st.push(encodeNumber(int64(ins.Opcode - OP_1 + 1)))
+		return nil
+	}
+
+	switch ins.Opcode {
+	case OP_VERIFY:
+		ok, err := st.popBool()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrVerifyFailed
+		}
+
+	case OP_RETURN:
+		return errors.New("OP_RETURN encountered")
+
+	case OP_DROP:
+		_, err := st.pop()
+		return err
+
+	case OP_DUP:
+		top, err := st.peek()
+		if err != nil {
+			return err
+		}
+		st.push(top)
+
+	case OP_SWAP:
+		a, err := st.pop()
+		if err != nil {
+			return err
+		}
+		b, err := st.pop()
+		if err != nil {
+			return err
+		}
+		st.push(a)
+		st.push(b)
+
+	case OP_SIZE:
+		top, err := st.peek()
+		if err != nil {
+			return err
+		}
+		st.push(encodeNumber(int64(len(top))))
+
+	case OP_EQUAL, OP_EQUALVERIFY:
+		a, err := st.pop()
+		if err != nil {
+			return err
+		}
+		b, err := st.pop()
+		if err != nil {
+			return err
+		}
+		equal := bytes.Equal(a, b)
+		if ins.Opcode == OP_EQUALVERIFY {
+			if !equal {
+				return ErrVerifyFailed
+			}
+			return nil
+		}
+		st.push(fromBool(equal))
+
+	case OP_SHA256:
+		data, err := st.pop()
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(data)
+		st.push(hash[:])
+
+	case OP_HASH160:
+		data, err := st.pop()
+		if err != nil {
+			return err
+		}
+		st.push(Hash160(data))
+
+	case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+		pubKey, err := st.pop()
+		if err != nil {
+			return err
+		}
+		signature, err := st.pop()
+		if err != nil {
+			return err
+		}
+		valid := len(signature) > 0 && checker.CheckSig(signature, pubKey, script)
+		if ins.Opcode == OP_CHECKSIGVERIFY {
+			if !valid {
+				return ErrVerifyFailed
+			}
+			return nil
+		}
+		st.push(fromBool(valid))
+
+	case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+		valid, err := checkMultiSig(st, script, checker)
+		if err != nil {
+			return err
+		}
+		if ins.Opcode == OP_CHECKMULTISIGVERIFY {
+			if !valid {
+				return ErrVerifyFailed
+			}
+			return nil
+		}
+		st.push(fromBool(valid))
+
+	case OP_CHECKLOCKTIMEVERIFY:
+		top, err := st.peek()
+		if err != nil {
+			return err
+		}
+		lockTime, err := decodeNumber(top, maxLockTimeLength)
+		if err != nil {
+			return err
+		}
+		if lockTime < 0 || !checker.CheckLockTime(lockTime) {
+			return fmt.Errorf("lock time %d not satisfied", lockTime)
+		}
+
+	case OP_CHECKSEQUENCEVERIFY:
+		top, err := st.peek()
+		if err != nil {
+			return err
+		}
+		sequence, err := decodeNumber(top, maxLockTimeLength)
+		if err != nil {
+			return err
+		}
+		if sequence < 0 || !checker.CheckSequence(sequence) {
+			return fmt.Errorf("relative lock time %d not satisfied", sequence)
+		}
+
+	default:
+		return fmt.Errorf("unsupported opcode 0x%02x", ins.Opcode)
+	}
+
+	return nil
+}
+
+// checkMultiSig consumes <dummy> <sig>... <m> <pubkey>... <n> from the stack.
+// Signatures have to appear in the same order as their public keys
+func checkMultiSig(st *stack, script []byte, checker Checker) (bool, error) {
+	n, err := st.popInt()
+	if err != nil {
+		return false, err
+	}
+	if n < 0 || n > maxMultiSigKeys {
+		return false, fmt.Errorf("invalid number of public keys %d", n)
+	}
+
+	pubKeys := make([][]byte, n)
+	for i := range pubKeys {
+		if pubKeys[i], err = st.pop(); err != nil {
+			return false, err
+		}
+	}
+
+	m, err := st.popInt()
+	if err != nil {
+		return false, err
+	}
+	if m < 0 || m > n {
+		return false, fmt.Errorf("invalid number of signatures %d", m)
+	}
+
+	signatures := make([][]byte, m)
+	for i := range signatures {
+		if signatures[i], err = st.pop(); err != nil {
+			return false, err
+		}
+	}
+
+	// The extra item consumed by CHECKMULTISIG has to be empty
+	dummy, err := st.pop()
+	if err != nil {
+		return false, err
+	}
+	if len(dummy) != 0 {
+		return false, errors.New("multisig dummy element must be empty")
+	}
+
+	// Items were popped in reverse, walk both lists from the last pushed
+	keyIdx := len(pubKeys) - 1
+	for sigIdx := len(signatures) - 1; sigIdx >= 0; sigIdx-- {
+		matched := false
+		for ; keyIdx >= 0 && !matched; keyIdx-- {
+			matched = len(signatures[sigIdx]) > 0 && checker.CheckSig(signatures[sigIdx], pubKeys[keyIdx], script)
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// Hash160 : data -> sha256 -> ripemd160
+func Hash160(data []byte) []byte {
+	hash := sha256.Sum256(data)
+
+	ripeHash := ripemd160.New()
+	_, err := ripeHash.Write(hash[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return ripeHash.Sum(nil)
+}
+
+func asBool(item []byte) bool {
+	for i, b := range item {
+		if b != 0 {
+			// Negative zero is false as well
+			return !(i == len(item)-1 && b == 0x80)
+		}
+	}
+	return false
+}
+
+func fromBool(value bool) []byte {
+	if value {
+		return []byte{1}
+	}
+	return []byte{}
+}
+
+// encodeNumber encodes n as a minimal little endian sign-magnitude number
+func encodeNumber(n int64) []byte {
+	if n == 0 {
+		return []byte{}
+	}
+
+	negative := n < 0
+	abs := uint64(n)
+	if negative {
+		abs = uint64(-n)
+	}
+
+	var result []byte
+	for abs > 0 {
+		result = append(result, byte(abs&0xff))
+		abs >>= 8
+	}
+
+	if result[len(result)-1]&0x80 != 0 {
+		extra := byte(0x00)
+		if negative {
+			extra = 0x80
+		}
+		result = append(result, extra)
+	} else if negative {
+		result[len(result)-1] |= 0x80
+	}
+
+	return result
+}
+
+func decodeNumber(data []byte, maxLength int) (int64, error) {
+	if len(data) > maxLength {
+		return 0, fmt.Errorf("number of %d bytes exceeds %d bytes", len(data), maxLength)
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	// Reject numbers that are not minimally encoded
+	last := data[len(data)-1]
+	if last&0x7f == 0 && (len(data) == 1 || data[len(data)-2]&0x80 == 0) {
+		return 0, errors.New("number is not minimally encoded")
+	}
+
+	var result int64
+	for i, b := range data {
+		result |= int64(b) << uint(8*i)
+	}
+
+	if last&0x80 != 0 {
+		result &= ^(int64(0x80) << uint(8*(len(data)-1)))
+		return -result, nil
+	}
+
+	return result, nil
+}
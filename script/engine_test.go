@@ -0,0 +1,200 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+// testChecker accepts a signature made of "sig" followed by the public key,
+// and lock times up to its own
+type testChecker struct {
+	lockTime int64
+	sequence int64
+}
+
+func (c testChecker) CheckSig(signature, pubKey, subScript []byte) bool {
+	return bytes.Equal(signature, sign(pubKey))
+}
+
+func (c testChecker) CheckLockTime(lockTime int64) bool {
+	return lockTime <= c.lockTime
+}
+
+func (c testChecker) CheckSequence(sequence int64) bool {
+	return sequence <= c.sequence
+}
+
+func sign(pubKey []byte) []byte {
+	return append([]byte("sig"), pubKey...)
+}
+
+var (
+	key1, key2, key3 = []byte("key1"), []byte("key2"), []byte("key3")
+
+	multiSig     = MultiSig(2, [][]byte{key1, key2, key3})
+	multiSigLock = PayToScriptHash(Hash160(multiSig))
+
+	preimage     = []byte("secret")
+	preimageHash = sha256.Sum256(preimage)
+	htlc         = HashTimeLock(preimageHash[:], Hash160(key1), Hash160(key2), 100)
+)
+
+func ops(opcodes ...byte) []byte {
+	return opcodes
+}
+
+func TestExecute(t *testing.T) {
+	checker := testChecker{lockTime: 100, sequence: 10}
+
+	tests := []struct {
+		name      string
+		unlocking []byte
+		locking   []byte
+		valid     bool
+	}{
+		{"p2pkh", PayToPubKeyHashUnlock(sign(key1), key1), PayToPubKeyHash(Hash160(key1)), true},
+		{"p2pkh other key", PayToPubKeyHashUnlock(sign(key2), key2), PayToPubKeyHash(Hash160(key1)), false},
+		{"p2pkh bad signature", PayToPubKeyHashUnlock(sign(key2), key1), PayToPubKeyHash(Hash160(key1)), false},
+		{"p2pkh empty signature", PayToPubKeyHashUnlock(nil, key1), PayToPubKeyHash(Hash160(key1)), false},
+		{"p2pkh missing key", NewBuilder().AddData(sign(key1)).Script(), PayToPubKeyHash(Hash160(key1)), false},
+		{"unlocking not push only", append(PayToPubKeyHashUnlock(sign(key1), key1), OP_DUP), PayToPubKeyHash(Hash160(key1)), false},
+
+		{"multisig 1 and 3", MultiSigUnlock([][]byte{sign(key1), sign(key3)}, multiSig), multiSigLock, true},
+		{"multisig 2 and 3", MultiSigUnlock([][]byte{sign(key2), sign(key3)}, multiSig), multiSigLock, true},
+		{"multisig out of order", MultiSigUnlock([][]byte{sign(key3), sign(key1)}, multiSig), multiSigLock, false},
+		{"multisig one signature", MultiSigUnlock([][]byte{sign(key1)}, multiSig), multiSigLock, false},
+		{"multisig same signature twice", MultiSigUnlock([][]byte{sign(key1), sign(key1)}, multiSig), multiSigLock, false},
+		{"multisig dummy not empty", append([]byte{OP_1}, MultiSigUnlock([][]byte{sign(key1), sign(key3)}, multiSig)[1:]...), multiSigLock, false},
+		{"multisig other redeem script", MultiSigUnlock([][]byte{sign(key1), sign(key3)}, MultiSig(1, [][]byte{key1, key3})), multiSigLock, false},
+
+		{"nested if", ops(OP_0, OP_1), ops(OP_IF, OP_IF, OP_RETURN, OP_ELSE, OP_1, OP_ENDIF, OP_ELSE, OP_RETURN, OP_ENDIF), true},
+		{"nested if other branch", ops(OP_1, OP_1), ops(OP_IF, OP_IF, OP_RETURN, OP_ELSE, OP_1, OP_ENDIF, OP_ELSE, OP_RETURN, OP_ENDIF), false},
+		{"skipped branch not executed", ops(OP_0), ops(OP_IF, OP_0, OP_IF, OP_RETURN, OP_ENDIF, OP_ELSE, OP_1, OP_ENDIF), true},
+		{"notif", ops(OP_0), ops(OP_NOTIF, OP_1, OP_ELSE, OP_RETURN, OP_ENDIF), true},
+		{"if without endif", ops(OP_1), ops(OP_IF, OP_1), false},
+		{"else without if", ops(OP_1), ops(OP_ELSE, OP_1), false},
+		{"endif without if", ops(OP_1), ops(OP_ENDIF, OP_1), false},
+		{"if on empty stack", nil, ops(OP_IF, OP_1, OP_ENDIF), false},
+
+		{"cltv reached", nil, NewBuilder().AddInt(100).AddOp(OP_CHECKLOCKTIMEVERIFY).Script(), true},
+		{"cltv not reached", nil, NewBuilder().AddInt(101).AddOp(OP_CHECKLOCKTIMEVERIFY).Script(), false},
+		{"cltv negative", nil, NewBuilder().AddInt(-1).AddOp(OP_CHECKLOCKTIMEVERIFY).AddOp(OP_DROP).AddOp(OP_1).Script(), false},
+		{"cltv empty stack", nil, ops(OP_CHECKLOCKTIMEVERIFY), false},
+		{"cltv 5 byte lock time", nil, NewBuilder().AddInt(1 << 32).AddOp(OP_CHECKLOCKTIMEVERIFY).Script(), false},
+		{"csv reached", nil, NewBuilder().AddInt(10).AddOp(OP_CHECKSEQUENCEVERIFY).Script(), true},
+		{"csv not reached", nil, NewBuilder().AddInt(11).AddOp(OP_CHECKSEQUENCEVERIFY).Script(), false},
+
+		{"htlc claim", HashTimeLockClaim(sign(key1), key1, preimage), htlc, true},
+		{"htlc claim wrong preimage", HashTimeLockClaim(sign(key1), key1, []byte("guess")), htlc, false},
+		{"htlc claim by sender", HashTimeLockClaim(sign(key2), key2, preimage), htlc, false},
+		{"htlc refund", HashTimeLockRefund(sign(key2), key2), htlc, true},
+		{"htlc refund by recipient", HashTimeLockRefund(sign(key1), key1), htlc, false},
+		{"hash lock", NewBuilder().AddData(sign(key1)).AddData(key1).AddData(preimage).Script(), HashLock(preimageHash[:], Hash160(key1)), true},
+		{"hash lock wrong preimage", NewBuilder().AddData(sign(key1)).AddData(key1).AddData(key1).Script(), HashLock(preimageHash[:], Hash160(key1)), false},
+
+		{"empty pushdata1", ops(OP_PUSHDATA1, 0), ops(OP_0, OP_EQUAL), true},
+		{"truncated pushdata2", ops(OP_PUSHDATA2, 1), ops(OP_1), false},
+		{"push past the end", ops(3, 1, 2), ops(OP_1), false},
+		{"return", nil, ops(OP_1, OP_RETURN), false},
+		{"false on top", ops(OP_1, OP_0), nil, false},
+		{"unknown opcode", nil, ops(OP_1, 0xff), false},
+	}
+
+	for _, test := range tests {
+		err := Execute(test.unlocking, test.locking, checker)
+		if test.valid && err != nil {
+			t.Errorf("%s: %s", test.name, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%s: invalid spend accepted", test.name)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		n       int64
+		encoded string
+	}{
+		{0, ""},
+		{1, "01"},
+		{-1, "81"},
+		{127, "7f"},
+		{-127, "ff"},
+		{128, "8000"},
+		{-128, "8080"},
+		{255, "ff00"},
+		{256, "0001"},
+		{-256, "0081"},
+		{1 << 31, "0000008000"},
+	}
+
+	for _, test := range tests {
+		encoded := encodeNumber(test.n)
+		if hex.EncodeToString(encoded) != test.encoded {
+			t.Errorf("encodeNumber(%d) = %x, want %s", test.n, encoded, test.encoded)
+		}
+		if n, err := decodeNumber(encoded, maxLockTimeLength); err != nil || n != test.n {
+			t.Errorf("decodeNumber(%x) = %d, %v, want %d", encoded, n, err, test.n)
+		}
+	}
+
+	for _, invalid := range []string{"00", "80", "0100", "0080", "ff0000"} {
+		data, _ := hex.DecodeString(invalid)
+		if _, err := decodeNumber(data, maxNumberLength); err == nil {
+			t.Errorf("decodeNumber(%s) accepted a number that is not minimal", invalid)
+		}
+	}
+	if _, err := decodeNumber(encodeNumber(1<<31), maxNumberLength); err == nil {
+		t.Error("decodeNumber accepted a number longer than the limit")
+	}
+}
+
+func TestPushes(t *testing.T) {
+	for _, length := range []int{0, 1, 75, 76, 255, 256, 0xffff, 0x10000} {
+		data := bytes.Repeat([]byte{7}, length)
+		script := NewBuilder().AddData(data).Script()
+
+		pushes, ok := ExtractPushes(script)
+		if !ok || len(pushes) != 1 || !bytes.Equal(pushes[0], data) {
+			t.Errorf("push of %d bytes does not round trip", length)
+			continue
+		}
+
+		var st stack
+		if err := run(script, &st, testChecker{}); err != nil || len(st) != 1 || !bytes.Equal(st[0], data) {
+			t.Errorf("push of %d bytes does not execute: %v", length, err)
+		}
+	}
+
+	// Every encoding of an empty push is read the same by both
+	for _, script := range [][]byte{{OP_0}, {OP_PUSHDATA1, 0}, {OP_PUSHDATA2, 0, 0}, {OP_PUSHDATA4, 0, 0, 0, 0}} {
+		pushes, ok := ExtractPushes(script)
+		if !ok || len(pushes) != 1 || len(pushes[0]) != 0 {
+			t.Errorf("ExtractPushes(%x) = %x, %v", script, pushes, ok)
+		}
+
+		var st stack
+		if err := run(script, &st, testChecker{}); err != nil || len(st) != 1 || len(st[0]) != 0 {
+			t.Errorf("run(%x) = %x, %v", script, st, err)
+		}
+	}
+}
+
+func TestStandardTemplates(t *testing.T) {
+	if hash, ok := ExtractPubKeyHash(PayToPubKeyHash(Hash160(key1))); !ok || !bytes.Equal(hash, Hash160(key1)) {
+		t.Error("ExtractPubKeyHash does not read back the key hash")
+	}
+	if hash, ok := ExtractScriptHash(multiSigLock); !ok || !bytes.Equal(hash, Hash160(multiSig)) {
+		t.Error("ExtractScriptHash does not read back the script hash")
+	}
+
+	required, pubKeys, ok := ExtractMultiSig(multiSig)
+	if !ok || required != 2 || len(pubKeys) != 3 || !bytes.Equal(pubKeys[2], key3) {
+		t.Errorf("ExtractMultiSig = %d, %q, %v", required, pubKeys, ok)
+	}
+	if _, _, ok := ExtractMultiSig(MultiSig(4, [][]byte{key1, key2, key3})); ok {
+		t.Error("ExtractMultiSig accepted more required signatures than keys")
+	}
+}
@@ -0,0 +1,166 @@
+package script
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Opcodes understood by the interpreter, the values match the ones used by
+// Bitcoin so that scripts read the same way
+const (
+	OP_0                   = byte(0x00)
+	OP_FALSE               = OP_0
+	OP_PUSHDATA1           = byte(0x4c)
+	OP_PUSHDATA2           = byte(0x4d)
+	OP_PUSHDATA4           = byte(0x4e)
+	OP_1NEGATE             = byte(0x4f)
+	OP_1                   = byte(0x51)
+	OP_TRUE                = OP_1
+	OP_16                  = byte(0x60)
+	OP_IF                  = byte(0x63)
+	OP_NOTIF               = byte(0x64)
+	OP_ELSE                = byte(0x67)
+	OP_ENDIF               = byte(0x68)
+	OP_VERIFY              = byte(0x69)
+	OP_RETURN              = byte(0x6a)
+	OP_DROP                = byte(0x75)
+	OP_DUP                 = byte(0x76)
+	OP_SWAP                = byte(0x7c)
+	OP_SIZE                = byte(0x82)
+	OP_EQUAL               = byte(0x87)
+	OP_EQUALVERIFY         = byte(0x88)
+	OP_SHA256              = byte(0xa8)
+	OP_HASH160             = byte(0xa9)
+	OP_CHECKSIG            = byte(0xac)
+	OP_CHECKSIGVERIFY      = byte(0xad)
+	OP_CHECKMULTISIG       = byte(0xae)
+	OP_CHECKMULTISIGVERIFY = byte(0xaf)
+	OP_CHECKLOCKTIMEVERIFY = byte(0xb1)
+	OP_CHECKSEQUENCEVERIFY = byte(0xb2)
+)
+
+var opcodeNames = map[byte]string{
+	OP_0:                   "OP_0",
+	OP_PUSHDATA1:           "OP_PUSHDATA1",
+	OP_PUSHDATA2:           "OP_PUSHDATA2",
+	OP_PUSHDATA4:           "OP_PUSHDATA4",
+	OP_1NEGATE:             "OP_1NEGATE",
+	OP_IF:                  "OP_IF",
+	OP_NOTIF:               "OP_NOTIF",
+	OP_ELSE:                "OP_ELSE",
+	OP_ENDIF:               "OP_ENDIF",
+	OP_VERIFY:              "OP_VERIFY",
+	OP_RETURN:              "OP_RETURN",
+	OP_DROP:                "OP_DROP",
+	OP_DUP:                 "OP_DUP",
+	OP_SWAP:                "OP_SWAP",
+	OP_SIZE:                "OP_SIZE",
+	OP_EQUAL:               "OP_EQUAL",
+	OP_EQUALVERIFY:         "OP_EQUALVERIFY",
+	OP_SHA256:              "OP_SHA256",
+	OP_HASH160:             "OP_HASH160",
+	OP_CHECKSIG:            "OP_CHECKSIG",
+	OP_CHECKSIGVERIFY:      "OP_CHECKSIGVERIFY",
+	OP_CHECKMULTISIG:       "OP_CHECKMULTISIG",
+	OP_CHECKMULTISIGVERIFY: "OP_CHECKMULTISIGVERIFY",
+	OP_CHECKLOCKTIMEVERIFY: "OP_CHECKLOCKTIMEVERIFY",
+	OP_CHECKSEQUENCEVERIFY: "OP_CHECKSEQUENCEVERIFY",
+}
+
+// instruction is a single parsed element of a script, Data is only set for
+// push operations, empty but not nil for pushes of no bytes but OP_0
+type instruction struct {
+	Opcode byte
+	Data   []byte
+}
+
+func (ins instruction) isPush() bool {
+	return ins.Opcode <= OP_16 && ins.Opcode != 0x50
+}
+
+// parse splits a script into its instructions
+func parse(script []byte) ([]instruction, error) {
+	var instructions []instruction
+
+	for i := 0; i < len(script); {
+		opcode := script[i]
+		i++
+
+		length := 0
+		switch {
+		case opcode > OP_0 && opcode < OP_PUSHDATA1:
+			length = int(opcode)
+		case opcode == OP_PUSHDATA1:
+			if i+1 > len(script) {
+				return nil, fmt.Errorf("malformed OP_PUSHDATA1 at %d", i-1)
+			}
+			length = int(script[i])
+			i++
+		case opcode == OP_PUSHDATA2:
+			if i+2 > len(script) {
+				return nil, fmt.Errorf("malformed OP_PUSHDATA2 at %d", i-1)
+			}
+			length = int(script[i]) | int(script[i+1])<<8
+			i += 2
+		case opcode == OP_PUSHDATA4:
+			if i+4 > len(script) {
+				return nil, fmt.Errorf("malformed OP_PUSHDATA4 at %d", i-1)
+			}
+			length = int(uint32(script[i]) | uint32(script[i+1])<<8 | uint32(script[i+2])<<16 | uint32(script[i+3])<<24)
+			i += 4
+		}
+
+		if length < 0 || length > len(script)-i {
+			return nil, fmt.Errorf("push of %d bytes exceeds the script at %d", length, i)
+		}
+
+		ins := instruction{Opcode: opcode}
+		if opcode > OP_0 && opcode <= OP_PUSHDATA4 {
+			ins.Data = script[i : i+length]
+		}
+		i += length
+
+		instructions = append(instructions, ins)
+	}
+
+	return instructions, nil
+}
+
+// IsPushOnly reports whether the script consists of data pushes only
+func IsPushOnly(script []byte) bool {
+	instructions, err := parse(script)
+	if err != nil {
+		return false
+	}
+
+	for _, ins := range instructions {
+		if !ins.isPush() {
+			return false
+		}
+	}
+	return true
+}
+
+// Disassemble renders a script in a human readable form
+func Disassemble(script []byte) string {
+	instructions, err := parse(script)
+	if err != nil {
+		return fmt.Sprintf("[invalid script: %s]", err)
+	}
+
+	var parts []string
+	for _, ins := range instructions {
+		switch {
+		case len(ins.Data) > 0:
+			parts = append(parts, fmt.Sprintf("%x", ins.Data))
+		case ins.Opcode >= OP_1 && ins.Opcode <= OP_16:
+			parts = append(parts, fmt.Sprintf("OP_%d", ins.Opcode-OP_1+1))
+		case opcodeNames[ins.Opcode] != "":
+			parts = append(parts, opcodeNames[ins.Opcode])
+		default:
+			parts = append(parts, fmt.Sprintf("OP_UNKNOWN(0x%02x)", ins.Opcode))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,190 @@
+package script
+
+import "bytes"
+
+const pubKeyHashLength = 20
+
+// PayToPubKeyHash locks an output to the owner of the public key hashing to
+// pubKeyHash: OP_DUP OP_HASH160 <pubKeyHash> OP_EQUALVERIFY OP_CHECKSIG
+func PayToPubKeyHash(pubKeyHash []byte) []byte {
+	return NewBuilder().
+		AddOp(OP_DUP).
+		AddOp(OP_HASH160).
+		AddData(pubKeyHash).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+// PayToPubKeyHashUnlock is the unlocking script spending a PayToPubKeyHash
+// output: <signature> <pubKey>
+func PayToPubKeyHashUnlock(signature, pubKey []byte) []byte {
+	return NewBuilder().AddData(signature).AddData(pubKey).Script()
+}
+
+// HashLock locks an output to whoever reveals the preimage of a sha256 hash
+// and signs with the key hashing to pubKeyHash
+func HashLock(hash, pubKeyHash []byte) []byte {
+	return NewBuilder().
+		AddOp(OP_SHA256).
+		AddData(hash).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_DUP).
+		AddOp(OP_HASH160).
+		AddData(pubKeyHash).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+// HashTimeLock locks an output to the recipient once the preimage of hash is
+// revealed, or back to the sender after the absolute lockTime passed
+func HashTimeLock(hash, recipientPubKeyHash, senderPubKeyHash []byte, lockTime int64) []byte {
+	return NewBuilder().
+		AddOp(OP_IF).
+		AddOp(OP_SHA256).
+		AddData(hash).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_DUP).
+		AddOp(OP_HASH160).
+		AddData(recipientPubKeyHash).
+		AddOp(OP_ELSE).
+		AddInt(lockTime).
+		AddOp(OP_CHECKLOCKTIMEVERIFY).
+		AddOp(OP_DROP).
+		AddOp(OP_DUP).
+		AddOp(OP_HASH160).
+		AddData(senderPubKeyHash).
+		AddOp(OP_ENDIF).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+// HashTimeLockClaim spends a HashTimeLock output with the preimage
+func HashTimeLockClaim(signature, pubKey, preimage []byte) []byte {
+	return NewBuilder().AddData(signature).AddData(pubKey).AddData(preimage).AddOp(OP_TRUE).Script()
+}
+
+// HashTimeLockRefund spends a HashTimeLock output after its lock time
+func HashTimeLockRefund(signature, pubKey []byte) []byte {
+	return NewBuilder().AddData(signature).AddData(pubKey).AddOp(OP_FALSE).Script()
+}
+
+// ExtractPubKeyHash returns the public key hash of a PayToPubKeyHash script
+func ExtractPubKeyHash(script []byte) ([]byte, bool) {
+	if len(script) != 25 {
+		return nil, false
+	}
+
+	template := PayToPubKeyHash(make([]byte, pubKeyHashLength))
+	pubKeyHash := script[3:23]
+	if !bytes.Equal(script[:3], template[:3]) || !bytes.Equal(script[23:], template[23:]) {
+		return nil, false
+	}
+
+	return pubKeyHash, true
+}
+
+// ExtractPushes returns the data pushed by a push only script
+func ExtractPushes(script []byte) ([][]byte, bool) {
+	instructions, err := parse(script)
+	if err != nil {
+		return nil, false
+	}
+
+	var pushes [][]byte
+	for _, ins := range instructions {
+		switch {
+		case ins.Data != nil:
+			pushes = append(pushes, ins.Data)
+		case ins.Opcode == OP_0:
+			pushes = append(pushes, []byte{})
+		case ins.isPush():
+			pushes = append(pushes, encodeNumber(int64(ins.Opcode)-int64(OP_1)+1))
+		default:
+			return nil, false
+		}
+	}
+
+	return pushes, true
+}
+
+// MultiSig is the redeem script of an m-of-n multisignature output:
+// OP_m <pubKey>... OP_n OP_CHECKMULTISIG
+func MultiSig(required int, pubKeys [][]byte) []byte {
+	builder := NewBuilder().AddInt(int64(required))
+	for _, pubKey := range pubKeys {
+		builder.AddData(pubKey)
+	}
+	return builder.AddInt(int64(len(pubKeys))).AddOp(OP_CHECKMULTISIG).Script()
+}
+
+// MultiSigUnlock spends a pay-to-script-hash output guarded by a MultiSig
+// redeem script, signatures must be ordered like their public keys
+func MultiSigUnlock(signatures [][]byte, redeemScript []byte) []byte {
+	builder := NewBuilder().AddOp(OP_0)
+	for _, signature := range signatures {
+		builder.AddData(signature)
+	}
+	return builder.AddData(redeemScript).Script()
+}
+
+// ExtractMultiSig returns the number of required signatures and the public
+// keys of a MultiSig redeem script
+func ExtractMultiSig(script []byte) (int, [][]byte, bool) {
+	instructions, err := parse(script)
+	if err != nil || len(instructions) < 4 {
+		return 0, nil, false
+	}
+
+	last := len(instructions) - 1
+	if instructions[last].Opcode != OP_CHECKMULTISIG {
+		return 0, nil, false
+	}
+
+	required, ok := smallInt(instructions[0])
+	total, ok2 := smallInt(instructions[last-1])
+	if !ok || !ok2 || total != last-2 || required < 1 || required > total {
+		return 0, nil, false
+	}
+
+	var pubKeys [][]byte
+	for _, ins := range instructions[1 : last-1] {
+		if ins.Data == nil {
+			return 0, nil, false
+		}
+		pubKeys = append(pubKeys, ins.Data)
+	}
+
+	return required, pubKeys, true
+}
+
+// PayToScriptHash locks an output to the script hashing to scriptHash, the
+// spender reveals the script as the last push of the unlocking script:
+// OP_HASH160 <scriptHash> OP_EQUAL
+func PayToScriptHash(scriptHash []byte) []byte {
+	return NewBuilder().AddOp(OP_HASH160).AddData(scriptHash).AddOp(OP_EQUAL).Script()
+}
+
+// ExtractScriptHash returns the script hash of a PayToScriptHash script
+func ExtractScriptHash(script []byte) ([]byte, bool) {
+	if !IsPayToScriptHash(script) {
+		return nil, false
+	}
+	return script[2:22], true
+}
+
+func IsPayToScriptHash(script []byte) bool {
+	return len(script) == 23 &&
+		script[0] == OP_HASH160 &&
+		script[1] == pubKeyHashLength &&
+		script[22] == OP_EQUAL
+}
+
+func smallInt(ins instruction) (int, bool) {
+	if ins.Opcode >= OP_1 && ins.Opcode <= OP_16 {
+		return int(ins.Opcode-OP_1) + 1, true
+	}
+	return 0, false
+}
@@ -0,0 +1,286 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	legacySessionFile = "wallets_%s.session"
+
+	scryptN   = 1 << 15
+	scryptR   = 8
+	scryptP   = 1
+	keyLength = 32
+	saltSize  = 16
+)
+
+// encryptedMagic starts wallet files encrypted with a passphrase, plaintext
+// files are a bare gob stream
+var encryptedMagic = []byte("ENCWALLET")
+
+var (
+	ErrWalletLocked    = errors.New("wallet is locked")
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+	ErrWalletEncrypted = errors.New("wallet is already encrypted")
+	ErrWalletPlaintext = errors.New("wallet is not encrypted")
+	ErrEmptyPassphrase = errors.New("passphrase must not be empty")
+)
+
+// PassphrasePrompt asks the user for the passphrase of an encrypted wallet,
+// without it locked wallets can not be opened
+var PassphrasePrompt func(prompt string) (string, error)
+
+// encryptedFileVersion authenticates the key derivation parameters, files
+// of version 0 only authenticate their content
+const encryptedFileVersion = 1
+
+// encryptedFile is the envelope of an encrypted wallet file, the key is
+// derived from the passphrase with scrypt and seals the gob stream with
+// AES-GCM
+type encryptedFile struct {
+	Version    int
+	Salt       []byte
+	N, R, P    int
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// walletKey is the key of an encrypted wallet, kept while it is open so that
+// it can be saved again
+type walletKey struct {
+	Salt    []byte
+	N, R, P int
+	Key     []byte
+}
+
+func newWalletKey(passphrase string) (*walletKey, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+
+	key := walletKey{Salt: make([]byte, saltSize), N: scryptN, R: scryptR, P: scryptP}
+	if _, err := rand.Read(key.Salt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	key.Key, err = scrypt.Key([]byte(passphrase), key.Salt, key.N, key.R, key.P, keyLength)
+	return &key, err
+}
+
+// IsEncrypted reports whether the wallet file is protected by a passphrase
+func (ws *Wallets) IsEncrypted() bool {
+	return ws.key != nil
+}
+
+// Encrypt protects the wallet with passphrase from the next save on
+func (ws *Wallets) Encrypt(passphrase string) error {
+	if ws.IsEncrypted() {
+		return ErrWalletEncrypted
+	}
+
+	key, err := newWalletKey(passphrase)
+	if err != nil {
+		return err
+	}
+	ws.key = key
+
+	return nil
+}
+
+// ChangePassphrase re-keys an encrypted wallet from the next save on
+func (ws *Wallets) ChangePassphrase(passphrase string) error {
+	if !ws.IsEncrypted() {
+		return ErrWalletPlaintext
+	}
+
+	key, err := newWalletKey(passphrase)
+	if err != nil {
+		return err
+	}
+	ws.key = key
+
+	return nil
+}
+
+// Unlock hands the key of the wallet to the running node, which keeps it in
+// memory until timeout passes. Meanwhile the commands of the node open the
+// wallet without the passphrase
+func (ws *Wallets) Unlock(nodeID string, timeout time.Duration) error {
+	if !ws.IsEncrypted() {
+		return ErrWalletPlaintext
+	}
+
+	_, err := askSession(nodeID, sessionRequest{Command: "unlock", Salt: ws.key.Salt, Key: ws.key.Key, Timeout: timeout})
+	return err
+}
+
+// Lock makes the node forget the key before the timeout of Unlock
+func Lock(nodeID string) error {
+	_, err := askSession(nodeID, sessionRequest{Command: "lock"})
+	if err == ErrNoNode {
+		return nil
+	}
+	return err
+}
+
+// sessionKey returns the key the node keeps for the wallet encrypted with
+// salt, if it is unlocked
+func sessionKey(nodeID string, salt []byte) ([]byte, bool) {
+	response, err := askSession(nodeID, sessionRequest{Command: "key", Salt: salt})
+	if err != nil || response.Key == nil {
+		return nil, false
+	}
+	return response.Key, true
+}
+
+// additionalData authenticates the envelope of an encrypted file along with
+// its content, so that the key derivation parameters can not be altered
+func (file *encryptedFile) additionalData() []byte {
+	if file.Version == 0 {
+		return encryptedMagic
+	}
+
+	content := bytes.NewBuffer(append([]byte{}, encryptedMagic...))
+	for _, num := range []int{file.Version, file.N, file.R, file.P} {
+		binary.Write(content, binary.BigEndian, int64(num))
+	}
+	content.Write(file.Salt)
+
+	return content.Bytes()
+}
+
+// checkParams refuses key derivation parameters too costly to try, or too
+// weak to have been written by this wallet
+func (file *encryptedFile) checkParams() error {
+	if file.N < 1<<14 || file.N > 1<<20 || file.N&(file.N-1) != 0 ||
+		file.R < 1 || file.R > 32 || file.P < 1 || file.P > 16 || len(file.Salt) < saltSize {
+		return errors.New("wallet file has invalid key derivation parameters")
+	}
+	return nil
+}
+
+func (ws *Wallets) seal(plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(ws.key.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	file := encryptedFile{Version: encryptedFileVersion, Salt: ws.key.Salt, N: ws.key.N, R: ws.key.R, P: ws.key.P}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return nil, err
+	}
+	file.Ciphertext = gcm.Seal(nil, file.Nonce, plaintext, file.additionalData())
+
+	content := bytes.NewBuffer(append([]byte{}, encryptedMagic...))
+	err = gob.NewEncoder(content).Encode(file)
+
+	return content.Bytes(), err
+}
+
+// open decrypts the content of an encrypted wallet file with the key kept by
+// the node when unlocked, or else with the passphrase asked for by
+// PassphrasePrompt
+func (ws *Wallets) open(content []byte, nodeID string) ([]byte, error) {
+	var file encryptedFile
+	err := gob.NewDecoder(bytes.NewReader(content[len(encryptedMagic):])).Decode(&file)
+	if err != nil {
+		return nil, err
+	}
+	if file.Version > encryptedFileVersion {
+		return nil, fmt.Errorf("wallet file version %d is not supported", file.Version)
+	}
+	if err := file.checkParams(); err != nil {
+		return nil, err
+	}
+
+	// Older versions kept the key of unlocked wallets in a file
+	os.Remove(dataFile(legacySessionFile, nodeID))
+
+	key := walletKey{Salt: file.Salt, N: file.N, R: file.R, P: file.P}
+
+	if sessionKey, ok := sessionKey(nodeID, file.Salt); ok {
+		key.Key = sessionKey
+	} else {
+		if PassphrasePrompt == nil {
+			return nil, ErrWalletLocked
+		}
+		passphrase, err := PassphrasePrompt("Wallet passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		key.Key, err = scrypt.Key([]byte(passphrase), file.Salt, file.N, file.R, file.P, keyLength)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	gcm, err := newGCM(key.Key)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != gcm.NonceSize() {
+		return nil, errors.New("wallet file has an invalid nonce")
+	}
+
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, file.additionalData())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	ws.key = &key
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writePrivateFile writes a file only its owner can read. The content goes
+// to a temporary file first, renamed over path once it is on disk, so that a
+// crash never leaves a truncated wallet behind
+func writePrivateFile(path string, content []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	// Created with mode 0600
+	file, err := ioutil.TempFile(dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,37 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWritePrivateFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wallets.data")
+
+	// Files of older versions may be readable by others
+	if err := ioutil.WriteFile(path, []byte("old content that is longer"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writePrivateFile(path, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil || string(content) != "new" {
+		t.Errorf("content = %q, %v", content, err)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("%d files left in the directory, want only the wallet", len(files))
+	}
+}
@@ -0,0 +1,144 @@
+package wallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// HardenedKeyStart is the first index of hardened children, whose keys can
+// not be derived from the parent public key
+const HardenedKeyStart = uint32(0x80000000)
+
+// masterKeySalts key the HMAC deriving the master key of a hierarchy of each
+// ECDSA key type, as defined by SLIP-10
+var masterKeySalts = map[KeyType][]byte{
+	KeyP256:      []byte("Nist256p1 seed"),
+	KeySecp256k1: []byte("Bitcoin seed"),
+}
+
+// ExtendedKey is a private key of a BIP32-style hierarchy together with the
+// chain code needed to derive its children
+type ExtendedKey struct {
+	Type      KeyType
+	Key       []byte // 32 byte private scalar
+	ChainCode []byte
+	Depth     uint8
+	Index     uint32
+}
+
+// NewMasterKey derives the root of the hierarchy of keyType from a seed.
+// Ed25519 is not supported, it can not derive the non-hardened children of
+// BIP44 paths
+func NewMasterKey(seed []byte, keyType KeyType) (*ExtendedKey, error) {
+	masterKeySalt, ok := masterKeySalts[keyType]
+	if !ok {
+		return nil, errors.New("only ECDSA keys can be derived from a seed")
+	}
+	n := keyType.curve().Params().N
+
+	data := seed
+	for {
+		mac := hmac.New(sha512.New, masterKeySalt)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		// Out of range keys are skipped by hashing again, the odds are
+		// negligible on both curves
+		key := new(big.Int).SetBytes(sum[:32])
+		if key.Sign() != 0 && key.Cmp(n) < 0 {
+			return &ExtendedKey{Type: keyType, Key: sum[:32], ChainCode: sum[32:]}, nil
+		}
+		data = sum
+	}
+}
+
+// Child derives the child key at index, hardened when index is at least
+// HardenedKeyStart
+func (k *ExtendedKey) Child(index uint32) *ExtendedKey {
+	curve := k.Type.curve()
+	n := curve.Params().N
+
+	var data []byte
+	if index >= HardenedKeyStart {
+		data = append([]byte{0x00}, k.Key...)
+	} else {
+		data = PrivateKey{k.Type, k.Key}.PublicKey()[1:]
+	}
+
+	indexBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(indexBytes, index)
+
+	for {
+		mac := hmac.New(sha512.New, k.ChainCode)
+		mac.Write(data)
+		mac.Write(indexBytes)
+		sum := mac.Sum(nil)
+
+		tweak := new(big.Int).SetBytes(sum[:32])
+		childKey := new(big.Int).Add(tweak, new(big.Int).SetBytes(k.Key))
+		childKey.Mod(childKey, n)
+
+		if tweak.Cmp(n) < 0 && childKey.Sign() != 0 {
+			key := make([]byte, 32)
+			childKey.FillBytes(key)
+			return &ExtendedKey{Type: k.Type, Key: key, ChainCode: sum[32:], Depth: k.Depth + 1, Index: index}
+		}
+
+		// SLIP-10 retries invalid children with the right half of the hash
+		data = append([]byte{0x01}, sum[32:]...)
+	}
+}
+
+// DerivePath follows a path such as m/44'/0'/0'/0/1 from the master key,
+// apostrophes mark hardened indexes
+func (k *ExtendedKey) DerivePath(path string) (*ExtendedKey, error) {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := k
+	for _, index := range indexes {
+		key = key.Child(index)
+	}
+	return key, nil
+}
+
+// PrivateKey returns the private key of the extended key
+func (k *ExtendedKey) PrivateKey() PrivateKey {
+	return PrivateKey{k.Type, k.Key}
+}
+
+// ParsePath returns the child indexes of a derivation path
+func ParsePath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("derivation path %q does not start with m", path)
+	}
+
+	var indexes []uint32
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h")
+		if hardened {
+			part = part[:len(part)-1]
+		}
+
+		index, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || uint32(index) >= HardenedKeyStart {
+			return nil, fmt.Errorf("invalid index %q in derivation path %q", part, path)
+		}
+
+		if hardened {
+			index += uint64(HardenedKeyStart)
+		}
+		indexes = append(indexes, uint32(index))
+	}
+
+	return indexes, nil
+}
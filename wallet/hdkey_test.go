@@ -0,0 +1,49 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// Test vector 1 of SLIP-10, the secp256k1 part is the one of BIP32
+// https://github.com/satoshilabs/slips/blob/master/slip-0010.md
+var hdSeed = "000102030405060708090a0b0c0d0e0f"
+
+var hdVectors = []struct {
+	keyType   KeyType
+	path      string
+	chainCode string
+	key       string
+}{
+	{KeyP256, "m", "beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea", "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2"},
+	{KeyP256, "m/0'", "3460cea53e6a6bb5fb391eeef3237ffd8724bf0a40e94943c98b83825342ee11", "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c"},
+	{KeyP256, "m/0'/1", "4187afff1aafa8445010097fb99d23aee9f599450c7bd140b6826ac22ba21d0c", "284e9d38d07d21e4e281b645089a94f4cf5a5a81369acf151a1c3a57f18b2129"},
+	{KeyP256, "m/0'/1/2'", "98c7514f562e64e74170cc3cf304ee1ce54d6b6da4f880f313e8204c2a185318", "694596e8a54f252c960eb771a3c41e7e32496d03b954aeb90f61635b8e092aa7"},
+	{KeySecp256k1, "m", "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+	{KeySecp256k1, "m/0'", "47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+	{KeySecp256k1, "m/0'/1", "2a7857631386ba23dacac34180dd1983734e444fdbf774041578e9b6adb37c19", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+	{KeySecp256k1, "m/0'/1/2'", "04466b9cc8e161e966409ca52986c584f07e9dc81f735db683c3ff6ec7b1503f", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+}
+
+func TestDerivationVectors(t *testing.T) {
+	seed, _ := hex.DecodeString(hdSeed)
+
+	for _, vector := range hdVectors {
+		master, err := NewMasterKey(seed, vector.keyType)
+		if err != nil {
+			t.Fatalf("NewMasterKey(%s): %s", vector.keyType, err)
+		}
+
+		key, err := master.DerivePath(vector.path)
+		if err != nil {
+			t.Fatalf("DerivePath(%s): %s", vector.path, err)
+		}
+
+		if hex.EncodeToString(key.ChainCode) != vector.chainCode {
+			t.Errorf("%s %s chain code = %x, want %s", vector.keyType, vector.path, key.ChainCode, vector.chainCode)
+		}
+		if hex.EncodeToString(key.Key) != vector.key {
+			t.Errorf("%s %s key = %x, want %s", vector.keyType, vector.path, key.Key, vector.key)
+		}
+	}
+}
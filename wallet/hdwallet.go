@@ -0,0 +1,142 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+)
+
+const (
+	hdPurpose = 44
+
+	// ReceiveBranch holds the addresses handed out to payers
+	ReceiveBranch = uint32(0)
+	// ChangeBranch holds the addresses receiving our own change
+	ChangeBranch = uint32(1)
+
+	// DefaultGapLimit is the number of unused addresses in a row after
+	// which discovery assumes no more addresses were handed out
+	DefaultGapLimit = 20
+)
+
+// HDChain derives every key of a deterministic wallet from a single seed
+// phrase, laid out as m/44'/coin type'/account'/branch/index
+type HDChain struct {
+	Mnemonic string
+	Seed     []byte
+	KeyType  KeyType
+	Accounts map[uint32]*HDAccount
+}
+
+// HDAccount tracks the next unused index of both branches of an account
+type HDAccount struct {
+	Next [2]uint32
+}
+
+// NewHDChain restores the key hierarchy of a seed phrase
+func NewHDChain(mnemonic, passphrase string, keyType KeyType) (*HDChain, error) {
+	if _, err := MnemonicToEntropy(mnemonic); err != nil {
+		return nil, err
+	}
+
+	seed := MnemonicToSeed(mnemonic, passphrase)
+	if _, err := NewMasterKey(seed, keyType); err != nil {
+		return nil, err
+	}
+
+	hd := HDChain{
+		Mnemonic: mnemonic,
+		Seed:     seed,
+		KeyType:  keyType,
+		Accounts: make(map[uint32]*HDAccount),
+	}
+	return &hd, nil
+}
+
+// HDPath returns the derivation path of a key
+func HDPath(account, branch, index uint32) string {
+	return fmt.Sprintf("m/%d'/%d'/%d'/%d/%d", hdPurpose, chaincfg.Active.HDCoinType, account, branch, index)
+}
+
+// Derive returns the wallet of the key at the given position
+func (hd *HDChain) Derive(account, branch, index uint32) *Wallet {
+	path := HDPath(account, branch, index)
+
+	master, err := NewMasterKey(hd.Seed, hd.KeyType)
+	Handle(err)
+	key, err := master.DerivePath(path)
+	Handle(err)
+
+	privateKey := key.PrivateKey()
+
+	return &Wallet{privateKey, privateKey.PublicKey(), path}
+}
+
+func (hd *HDChain) account(account uint32) *HDAccount {
+	if hd.Accounts == nil {
+		hd.Accounts = make(map[uint32]*HDAccount)
+	}
+	if _, ok := hd.Accounts[account]; !ok {
+		hd.Accounts[account] = &HDAccount{}
+	}
+	return hd.Accounts[account]
+}
+
+// AddHDWallet derives the next unused address of a branch of an account
+func (ws Wallets) AddHDWallet(account, branch uint32) (string, error) {
+	if ws.HD == nil {
+		return "", errors.New("wallet has no seed phrase")
+	}
+
+	acc := ws.HD.account(account)
+	wallet := ws.HD.Derive(account, branch, acc.Next[branch])
+	acc.Next[branch]++
+
+	address := string(wallet.Address())
+	ws.Wallets[address] = wallet
+
+	return address, nil
+}
+
+// Discover derives the addresses of the seed, account by account, until
+// gapLimit addresses in a row were never used on chain, and returns the
+// number of used addresses found
+func (ws Wallets) Discover(gapLimit int, used func(pubKeyHash []byte) bool) (int, error) {
+	if ws.HD == nil {
+		return 0, errors.New("wallet has no seed phrase")
+	}
+
+	found := 0
+	for account := uint32(0); account < HardenedKeyStart; account++ {
+		accountUsed := false
+
+		for _, branch := range []uint32{ReceiveBranch, ChangeBranch} {
+			gap := 0
+			for index := uint32(0); gap < gapLimit; index++ {
+				wallet := ws.HD.Derive(account, branch, index)
+				if !used(PublicKeyHash(wallet.PublicKey)) {
+					gap++
+					continue
+				}
+
+				gap = 0
+				accountUsed = true
+				found++
+
+				ws.Wallets[string(wallet.Address())] = wallet
+				acc := ws.HD.account(account)
+				if acc.Next[branch] <= index {
+					acc.Next[branch] = index + 1
+				}
+			}
+		}
+
+		// Accounts are only created once the previous one was used
+		if !accountUsed {
+			break
+		}
+	}
+
+	return found, nil
+}
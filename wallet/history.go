@@ -0,0 +1,127 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// TxRecord is a transaction paying to or spending from the wallet
+type TxRecord struct {
+	ID        []byte
+	Received  int      // Value of the outputs paying to the wallet
+	Sent      int      // Value of the wallet outputs spent by the inputs
+	Addresses []string // Wallet addresses involved
+	Coinbase  bool
+	Height    int   // Height of the block, -1 while unconfirmed
+	Timestamp int64 // Block time, or creation time while unconfirmed
+	Memo      string
+	Dropped   bool // Pending transaction that will not be mined anymore
+}
+
+// Net is the effect of the transaction on the wallet balance
+func (rec *TxRecord) Net() int {
+	return rec.Received - rec.Sent
+}
+
+// Confirmations returns the number of blocks on top of, and including, the
+// block of the transaction
+func (rec *TxRecord) Confirmations(bestHeight int) int {
+	if rec.Height < 0 {
+		return 0
+	}
+	return bestHeight - rec.Height + 1
+}
+
+// RecordTransaction adds a transaction to the history, or updates the block
+// it was mined in while keeping its memo
+func (ws *Wallets) RecordTransaction(rec TxRecord) {
+	if ws.Transactions == nil {
+		ws.Transactions = make(map[string]*TxRecord)
+	}
+
+	txID := hex.EncodeToString(rec.ID)
+	if known, ok := ws.Transactions[txID]; ok && rec.Memo == "" {
+		rec.Memo = known.Memo
+	}
+	ws.Transactions[txID] = &rec
+}
+
+// SetMemo attaches a note to a transaction of the history
+func (ws *Wallets) SetMemo(txID string, memo string) error {
+	rec, ok := ws.Transactions[txID]
+	if !ok {
+		return fmt.Errorf("transaction %s is not in the wallet history", txID)
+	}
+	rec.Memo = memo
+	return nil
+}
+
+// ListTransactions returns the history oldest first, unconfirmed
+// transactions last
+func (ws *Wallets) ListTransactions() []*TxRecord {
+	var records []*TxRecord
+	for _, rec := range ws.Transactions {
+		records = append(records, rec)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if (a.Height < 0) != (b.Height < 0) {
+			return b.Height < 0
+		}
+		if a.Height != b.Height {
+			return a.Height < b.Height
+		}
+		return a.Timestamp < b.Timestamp
+	})
+
+	return records
+}
+
+// SetLabel names an address, of the wallet or of a counterparty, an empty
+// label removes it
+func (ws *Wallets) SetLabel(address, label string) error {
+	if !ValidateAddress(address) {
+		return fmt.Errorf("address %s is not valid", address)
+	}
+
+	if ws.Labels == nil {
+		ws.Labels = make(map[string]string)
+	}
+	if label == "" {
+		delete(ws.Labels, address)
+	} else {
+		ws.Labels[address] = label
+	}
+
+	return nil
+}
+
+func (ws Wallets) Label(address string) string {
+	return ws.Labels[address]
+}
+
+// LockHashes maps the hex public key or script hash of every address the
+// wallet owns or watches to the address
+func (ws Wallets) LockHashes() map[string]string {
+	hashes := make(map[string]string)
+
+	add := func(address string) {
+		if _, lockHash, err := DecodeAddress(address); err == nil {
+			hashes[hex.EncodeToString(lockHash)] = address
+		}
+	}
+
+	for address := range ws.Wallets {
+		add(address)
+	}
+	for address := range ws.MultiSigs {
+		add(address)
+	}
+	for address := range ws.WatchOnly {
+		add(address)
+	}
+
+	return hashes
+}
@@ -0,0 +1,291 @@
+package wallet
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+)
+
+// KeyType is the signature scheme of a key. Public keys and signatures start
+// with their key type so that verification knows which scheme to use
+type KeyType byte
+
+const (
+	// KeyP256 is ECDSA over NIST P-256, the original key type
+	KeyP256 KeyType = iota
+	// KeySecp256k1 is ECDSA over secp256k1, as used by Bitcoin hardware
+	// signers
+	KeySecp256k1
+	// KeyEd25519 is EdDSA over Curve25519, the fastest to verify
+	KeyEd25519
+)
+
+var keyTypeNames = map[KeyType]string{
+	KeyP256:      "p256",
+	KeySecp256k1: "secp256k1",
+	KeyEd25519:   "ed25519",
+}
+
+func (t KeyType) String() string {
+	if name, ok := keyTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", byte(t))
+}
+
+// ParseKeyType returns the key type of a name printed by KeyType.String
+func ParseKeyType(name string) (KeyType, error) {
+	for t, typeName := range keyTypeNames {
+		if strings.EqualFold(name, typeName) {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key type %q, use p256, secp256k1 or ed25519", name)
+}
+
+// AddressVersion is the version byte of the addresses of the key type on the
+// active network
+func (t KeyType) AddressVersion() byte {
+	switch t {
+	case KeySecp256k1:
+		return chaincfg.Active.Secp256k1PubKeyHashAddrID
+	case KeyEd25519:
+		return chaincfg.Active.Ed25519PubKeyHashAddrID
+	}
+	return chaincfg.Active.PubKeyHashAddrID
+}
+
+// curve is the curve of ECDSA key types, nil for Ed25519
+func (t KeyType) curve() elliptic.Curve {
+	switch t {
+	case KeyP256:
+		return elliptic.P256()
+	case KeySecp256k1:
+		return S256()
+	}
+	return nil
+}
+
+// scalarLength is the size of private keys, coordinates and signature halves
+// of both ECDSA curves
+const scalarLength = 32
+
+// PrivateKey is a private key of any supported type
+type PrivateKey struct {
+	Type KeyType
+	Key  []byte // Private scalar for ECDSA, seed for Ed25519
+}
+
+// GenerateKey creates a random private key
+func GenerateKey(keyType KeyType) (PrivateKey, error) {
+	if keyType == KeyEd25519 {
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return PrivateKey{}, err
+		}
+		return PrivateKey{keyType, private.Seed()}, nil
+	}
+
+	curve := keyType.curve()
+	if curve == nil {
+		return PrivateKey{}, fmt.Errorf("unknown key type %d", keyType)
+	}
+
+	private, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return PrivateKey{}, err
+	}
+
+	key := make([]byte, scalarLength)
+	private.D.FillBytes(key)
+
+	return PrivateKey{keyType, key}, nil
+}
+
+// NewPrivateKey checks the raw bytes of a key of the given type
+func NewPrivateKey(keyType KeyType, key []byte) (PrivateKey, error) {
+	if len(key) != scalarLength {
+		return PrivateKey{}, errors.New("private key has an invalid length")
+	}
+
+	if keyType != KeyEd25519 {
+		curve := keyType.curve()
+		if curve == nil {
+			return PrivateKey{}, fmt.Errorf("unknown key type %d", keyType)
+		}
+
+		d := new(big.Int).SetBytes(key)
+		if d.Sign() == 0 || d.Cmp(curve.Params().N) >= 0 {
+			return PrivateKey{}, errors.New("private key is out of range")
+		}
+	}
+
+	return PrivateKey{keyType, key}, nil
+}
+
+
+// PublicKey returns the public key tagged with its type: type || SEC1
+// compressed point for ECDSA keys, type || key for Ed25519
+func (k PrivateKey) PublicKey() []byte {
+	if k.Type == KeyEd25519 {
+		public := ed25519.NewKeyFromSeed(k.Key).Public().(ed25519.PublicKey)
+		return append([]byte{byte(k.Type)}, public...)
+	}
+	if k.Type == KeySecp256k1 {
+		return append([]byte{byte(k.Type)}, secp256k1PublicKey(k.Key)...)
+	}
+
+	private, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), k.Key)
+	Handle(err)
+	public := private.PublicKey
+	return append([]byte{byte(k.Type)}, elliptic.MarshalCompressed(public.Curve, public.X, public.Y)...)
+}
+
+// Sign signs a hash, the signature is tagged with the key type: type || r || s
+// with both 32 bytes wide and s in the lower half of the order for ECDSA keys,
+// type || signature for Ed25519. Both schemes are deterministic, signing the
+// same hash twice gives the same signature
+func (k PrivateKey) Sign(hash []byte) ([]byte, error) {
+	signature := []byte{byte(k.Type)}
+
+	if k.Type == KeyEd25519 {
+		return append(signature, ed25519.Sign(ed25519.NewKeyFromSeed(k.Key), hash)...), nil
+	}
+
+	extra, err := signingExtra()
+	if err != nil {
+		return nil, err
+	}
+
+	if k.Type == KeySecp256k1 {
+		return append(signature, signSecp256k1(k.Key, hash, extra)...), nil
+	}
+
+	r, s, err := k.signECDSA(hash, extra)
+	if err != nil {
+		return nil, err
+	}
+
+	// (r, n - s) is valid as well, only the low one is accepted so that
+	// signatures, and the transaction ids, can not be altered
+	n := k.Type.curve().Params().N
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		s.Sub(n, s)
+	}
+
+	rs := make([]byte, 2*scalarLength)
+	r.FillBytes(rs[:scalarLength])
+	s.FillBytes(rs[scalarLength:])
+
+	return append(signature, rs...), nil
+}
+
+// parsePublicKey splits a tagged public key into its type and the key: an
+// *ecdsa.PublicKey on P-256, a *secp256k1.PublicKey or an ed25519.PublicKey.
+// ECDSA points are SEC1 encoded, compressed or not
+func parsePublicKey(publicKey []byte) (KeyType, crypto.PublicKey, bool) {
+	if len(publicKey) < 2 {
+		return 0, nil, false
+	}
+
+	keyType, key := KeyType(publicKey[0]), publicKey[1:]
+	switch keyType {
+	case KeyEd25519:
+		if len(key) != ed25519.PublicKeySize {
+			return 0, nil, false
+		}
+		return keyType, ed25519.PublicKey(key), true
+
+	case KeySecp256k1:
+		public := parseSecp256k1PublicKey(key)
+		if public == nil {
+			return 0, nil, false
+		}
+		return keyType, public, true
+
+	case KeyP256:
+		var x, y *big.Int
+		switch {
+		case len(key) == 1+scalarLength && (key[0] == 0x02 || key[0] == 0x03):
+			x, y = elliptic.UnmarshalCompressed(elliptic.P256(), key)
+		case len(key) == 1+2*scalarLength && key[0] == 0x04:
+			x, y = elliptic.Unmarshal(elliptic.P256(), key)
+		}
+		if x == nil {
+			return 0, nil, false
+		}
+		return keyType, &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, true
+	}
+
+	return 0, nil, false
+}
+
+// ValidatePublicKey reports whether publicKey is a tagged public key of a
+// supported type
+func ValidatePublicKey(publicKey []byte) bool {
+	_, _, ok := parsePublicKey(publicKey)
+	return ok
+}
+
+// CheckSignatureEncoding reports whether publicKey and signature are encoded
+// the way Sign encodes them and are of the same type, without checking that
+// the signature is valid
+func CheckSignatureEncoding(publicKey, signature []byte) bool {
+	keyType, _, ok := parsePublicKey(publicKey)
+	return ok && checkSignatureEncoding(keyType, signature)
+}
+
+func checkSignatureEncoding(keyType KeyType, signature []byte) bool {
+	if len(signature) < 2 || KeyType(signature[0]) != keyType {
+		return false
+	}
+	signature = signature[1:]
+
+	if keyType == KeyEd25519 {
+		return len(signature) == ed25519.SignatureSize
+	}
+	if len(signature) != 2*scalarLength {
+		return false
+	}
+
+	r := new(big.Int).SetBytes(signature[:scalarLength])
+	s := new(big.Int).SetBytes(signature[scalarLength:])
+
+	n := keyType.curve().Params().N
+	return r.Sign() != 0 && r.Cmp(n) < 0 && s.Sign() != 0 && s.Cmp(new(big.Int).Rsh(n, 1)) <= 0
+}
+
+// VerifySignature checks a tagged signature of hash against a tagged public
+// key, both must be of the same type. Signatures not encoded the way Sign
+// encodes them are rejected
+func VerifySignature(publicKey, hash, signature []byte) bool {
+	keyType, key, ok := parsePublicKey(publicKey)
+	if !ok || !checkSignatureEncoding(keyType, signature) {
+		return false
+	}
+	signature = signature[1:]
+
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, hash, signature)
+
+	case *secp256k1.PublicKey:
+		return verifySecp256k1(key, hash, signature)
+
+	case *ecdsa.PublicKey:
+		r := new(big.Int).SetBytes(signature[:scalarLength])
+		s := new(big.Int).SetBytes(signature[scalarLength:])
+		return ecdsa.Verify(key, hash, r, s)
+	}
+
+	return false
+}
@@ -0,0 +1,77 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"log"
+	"math/big"
+)
+
+// legacyWallets is the layout of wallet files written before keys were tagged
+// with their type, when every key was an ecdsa.PrivateKey on P-256. Only what
+// still means the same is read back
+type legacyWallets struct {
+	Wallets       map[string]*legacyWallet
+	CoinSelection string
+	HD            *HDChain
+	Labels        map[string]string
+}
+
+// legacyWallet keeps the scalar of the ecdsa.PrivateKey, the curve it was
+// stored with is skipped by the decoder
+type legacyWallet struct {
+	PrivateKey struct{ D *big.Int }
+	Path       string
+}
+
+// hasUntypedKeys reports whether keys were lost decoding the file, legacy
+// ecdsa.PrivateKey values share no field with PrivateKey
+func (ws *Wallets) hasUntypedKeys() bool {
+	for _, w := range ws.Wallets {
+		if w == nil || len(w.PrivateKey.Key) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// loadLegacy reads a wallet file of the legacy layout into ws. The keys are
+// kept but their addresses change along with the encoding of public keys,
+// labels follow the keys to the new addresses. The history, multisig and
+// watch-only addresses refer to untagged public keys and are left out, rescan
+// rebuilds the history
+func (ws *Wallets) loadLegacy(content []byte) error {
+	var legacy legacyWallets
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&legacy); err != nil {
+		return err
+	}
+	if len(legacy.Wallets) == 0 && legacy.HD == nil {
+		return errors.New("no keys found")
+	}
+
+	for oldAddress, old := range legacy.Wallets {
+		if old == nil || old.PrivateKey.D == nil || old.PrivateKey.D.BitLen() > 8*privateKeyLength {
+			return errors.New("invalid legacy key")
+		}
+
+		privateKey, err := NewPrivateKey(KeyP256, old.PrivateKey.D.FillBytes(make([]byte, privateKeyLength)))
+		if err != nil {
+			return err
+		}
+
+		wallet := Wallet{privateKey, privateKey.PublicKey(), old.Path}
+		address := string(wallet.Address())
+		ws.Wallets[address] = &wallet
+
+		if label, ok := legacy.Labels[oldAddress]; ok {
+			ws.Labels[address] = label
+		}
+		log.Printf("Legacy address %s is now %s", oldAddress, address)
+	}
+
+	ws.CoinSelection = legacy.CoinSelection
+	ws.HD = legacy.HD
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/gob"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+)
+
+// legacyCurve is stored the way elliptic.P256() used to be, a struct
+// embedding its parameters registered under the name of the old type
+type legacyCurve struct {
+	*elliptic.CurveParams
+}
+
+// legacyFileWallet is the wallet layout written before keys were tagged
+type legacyFileWallet struct {
+	PrivateKey ecdsa.PrivateKey
+	PublicKey  []byte
+	Path       string
+}
+
+type legacyFile struct {
+	Wallets map[string]*legacyFileWallet
+	Labels  map[string]string
+}
+
+func TestLoadLegacyWallets(t *testing.T) {
+	dataDir := chaincfg.Active.DataDir
+	defer func() { chaincfg.Active.DataDir = dataDir }()
+	chaincfg.Active.DataDir = t.TempDir()
+
+	d, _ := new(big.Int).SetString("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721", 16)
+	oldKey := ecdsa.PrivateKey{D: d}
+	oldKey.PublicKey.Curve = legacyCurve{elliptic.P256().Params()}
+	oldKey.PublicKey.X, oldKey.PublicKey.Y = elliptic.P256().ScalarBaseMult(d.Bytes())
+
+	oldAddress := "1LegacyAddress"
+	file := legacyFile{
+		Wallets: map[string]*legacyFileWallet{oldAddress: {oldKey, append(oldKey.X.Bytes(), oldKey.Y.Bytes()...), ""}},
+		Labels:  map[string]string{oldAddress: "savings"},
+	}
+
+	gob.RegisterName("crypto/elliptic.p256Curve", legacyCurve{})
+	var content bytes.Buffer
+	if err := gob.NewEncoder(&content).Encode(file); err != nil {
+		t.Fatal(err)
+	}
+	walletPath := dataFile(walletDBFile, "legacy")
+	if err := writePrivateFile(walletPath, content.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pass := range []string{"upgrade", "reload"} {
+		wallets, err := CreateWallets("legacy")
+		if err != nil {
+			t.Fatalf("%s: %s", pass, err)
+		}
+		if len(wallets.Wallets) != 1 {
+			t.Fatalf("%s: %d wallets, want 1", pass, len(wallets.Wallets))
+		}
+		for address, w := range wallets.Wallets {
+			if w.PrivateKey.Type != KeyP256 || !bytes.Equal(w.PrivateKey.Key, d.FillBytes(make([]byte, 32))) {
+				t.Errorf("%s: key %v, want the legacy P-256 key", pass, w.PrivateKey)
+			}
+			if wallets.Label(address) != "savings" {
+				t.Errorf("%s: label %q, want savings", pass, wallets.Label(address))
+			}
+		}
+	}
+
+	backup, err := ioutil.ReadFile(walletPath + legacyBackupSuffix)
+	if err != nil || !bytes.Equal(backup, content.Bytes()) {
+		t.Errorf("legacy file not kept: %v", err)
+	}
+}
+
+func TestLoadCorruptWallets(t *testing.T) {
+	dataDir := chaincfg.Active.DataDir
+	defer func() { chaincfg.Active.DataDir = dataDir }()
+	chaincfg.Active.DataDir = t.TempDir()
+
+	walletPath := dataFile(walletDBFile, "corrupt")
+	if err := writePrivateFile(walletPath, []byte("not a wallet")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := CreateWallets("corrupt"); err == nil || os.IsNotExist(err) {
+		t.Errorf("CreateWallets of a corrupt file: %v", err)
+	}
+}
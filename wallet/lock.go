@@ -0,0 +1,30 @@
+package wallet
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const walletLockFile = "wallets_%s.lock"
+
+// LockWallets waits until no other process of the node holds the wallet and
+// takes it exclusively, so that commands spending from the same wallet do not
+// pick the same outputs. The returned function releases it
+func LockWallets(nodeID string) (func(), error) {
+	lockPath := dataFile(walletLockFile, nodeID)
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0700); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// Closing the file releases the lock
+	return func() { file.Close() }, nil
+}
@@ -0,0 +1,20 @@
+package wallet
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const lockfileExclusiveLock = 0x2
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+func lockFile(file *os.File) error {
+	var overlapped syscall.Overlapped
+	ok, _, err := procLockFileEx.Call(file.Fd(), lockfileExclusiveLock, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if ok == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	bitsPerWord     = 11
+	seedIterations  = 2048
+	seedSaltPrefix  = "mnemonic"
+	minEntropyBits  = 128
+	maxEntropyBits  = 256
+	entropyBitsStep = 32
+)
+
+// NewMnemonic generates a seed phrase of words words: 12, 15, 18, 21 or 24
+func NewMnemonic(words int) (string, error) {
+	// Every 3 words carry 32 bits of entropy and 1 bit of checksum
+	entropyBits := words / 3 * entropyBitsStep
+	if words%3 != 0 || entropyBits < minEntropyBits || entropyBits > maxEntropyBits {
+		return "", fmt.Errorf("invalid number of words %d", words)
+	}
+
+	entropy := make([]byte, entropyBits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+
+	return EntropyToMnemonic(entropy)
+}
+
+// EntropyToMnemonic encodes entropy followed by its checksum as words of 11
+// bits each
+func EntropyToMnemonic(entropy []byte) (string, error) {
+	entropyBits := len(entropy) * 8
+	if entropyBits%entropyBitsStep != 0 || entropyBits < minEntropyBits || entropyBits > maxEntropyBits {
+		return "", fmt.Errorf("invalid entropy length %d", len(entropy))
+	}
+
+	checksumBits := entropyBits / entropyBitsStep
+	hash := sha256.Sum256(entropy)
+
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, uint(checksumBits))
+	data.Or(data, big.NewInt(int64(hash[0]>>(8-checksumBits))))
+
+	words := make([]string, (entropyBits+checksumBits)/bitsPerWord)
+	mask := big.NewInt(1<<bitsPerWord - 1)
+	for i := len(words) - 1; i >= 0; i-- {
+		index := new(big.Int).And(data, mask)
+		words[i] = wordList[index.Int64()]
+		data.Rsh(data, bitsPerWord)
+	}
+
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy decodes a seed phrase and verifies its checksum
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words)%3 != 0 || len(words) < 12 || len(words) > 24 {
+		return nil, fmt.Errorf("invalid number of words %d", len(words))
+	}
+
+	data := new(big.Int)
+	for _, word := range words {
+		index, ok := wordIndex(word)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a seed phrase word", word)
+		}
+		data.Lsh(data, bitsPerWord)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+
+	checksumBits := len(words) / 3
+	checksum := new(big.Int).And(data, big.NewInt(1<<uint(checksumBits)-1))
+	data.Rsh(data, uint(checksumBits))
+
+	entropy := make([]byte, checksumBits*entropyBitsStep/8)
+	data.FillBytes(entropy)
+
+	hash := sha256.Sum256(entropy)
+	if checksum.Int64() != int64(hash[0]>>(8-checksumBits)) {
+		return nil, errors.New("seed phrase checksum mismatch")
+	}
+
+	return entropy, nil
+}
+
+// ValidateMnemonic reports whether the seed phrase is well formed
+func ValidateMnemonic(mnemonic string) bool {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err == nil
+}
+
+// MnemonicToSeed stretches the seed phrase, protected by an optional
+// passphrase, into the 64 byte seed of the key hierarchy
+func MnemonicToSeed(mnemonic, passphrase string) []byte {
+	// BIP39 normalizes both to NFKD first, which leaves the plain ASCII
+	// words of the English list and ASCII passphrases untouched
+	normalized := strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2.Key([]byte(normalized), []byte(seedSaltPrefix+passphrase), seedIterations, 64, sha512.New)
+}
+
+func wordIndex(word string) (int, bool) {
+	word = strings.ToLower(word)
+	lo, hi := 0, len(wordList)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if wordList[mid] < word {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, lo < len(wordList) && wordList[lo] == word
+}
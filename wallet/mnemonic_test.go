@@ -0,0 +1,59 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// BIP39 test vectors, https://github.com/trezor/python-mnemonic/blob/master/vectors.json
+var mnemonicVectors = []struct {
+	entropy  string
+	mnemonic string
+	seed     string
+}{
+	{
+		"00000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		"legal winner thank year wave sausage worth useful legal winner thank yellow",
+		"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+	},
+	{
+		"ffffffffffffffffffffffffffffffff",
+		"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		"ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+	},
+}
+
+func TestMnemonicVectors(t *testing.T) {
+	for _, vector := range mnemonicVectors {
+		entropy, _ := hex.DecodeString(vector.entropy)
+
+		mnemonic, err := EntropyToMnemonic(entropy)
+		if err != nil {
+			t.Fatalf("EntropyToMnemonic(%s): %s", vector.entropy, err)
+		}
+		if mnemonic != vector.mnemonic {
+			t.Errorf("EntropyToMnemonic(%s) = %q, want %q", vector.entropy, mnemonic, vector.mnemonic)
+		}
+
+		decoded, err := MnemonicToEntropy(vector.mnemonic)
+		if err != nil || hex.EncodeToString(decoded) != vector.entropy {
+			t.Errorf("MnemonicToEntropy(%q) = %x, %v, want %s", vector.mnemonic, decoded, err, vector.entropy)
+		}
+
+		seed := MnemonicToSeed(vector.mnemonic, "TREZOR")
+		if hex.EncodeToString(seed) != vector.seed {
+			t.Errorf("MnemonicToSeed(%q) = %x, want %s", vector.mnemonic, seed, vector.seed)
+		}
+	}
+}
+
+func TestMnemonicChecksum(t *testing.T) {
+	if ValidateMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon") {
+		t.Error("mnemonic with a wrong checksum word is valid")
+	}
+}
@@ -0,0 +1,49 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+	"gitlab.com/thesepehrm/first-blockchain/script"
+)
+
+const maxMultiSigKeys = 16
+
+// MultiSig is an m-of-n multisignature account, funds sent to its address
+// can only be spent with signatures of Required of the PublicKeys
+type MultiSig struct {
+	Required     int
+	PublicKeys   [][]byte
+	RedeemScript []byte
+}
+
+func NewMultiSig(required int, publicKeys [][]byte) (*MultiSig, error) {
+	if len(publicKeys) == 0 || len(publicKeys) > maxMultiSigKeys {
+		return nil, fmt.Errorf("a multisig account takes 1 to %d public keys", maxMultiSigKeys)
+	}
+	if required < 1 || required > len(publicKeys) {
+		return nil, fmt.Errorf("required signatures must be between 1 and %d", len(publicKeys))
+	}
+
+	for i, publicKey := range publicKeys {
+		if !ValidatePublicKey(publicKey) {
+			return nil, fmt.Errorf("public key %x is not valid", publicKey)
+		}
+		for _, other := range publicKeys[:i] {
+			if bytes.Equal(publicKey, other) {
+				return nil, errors.New("duplicate public key")
+			}
+		}
+	}
+
+	redeemScript := script.MultiSig(required, publicKeys)
+
+	return &MultiSig{required, publicKeys, redeemScript}, nil
+}
+
+// Address : redeem script -> hash160 -> base58 address with the script hash version
+func (m MultiSig) Address() []byte {
+	return EncodeAddress(chaincfg.Active.ScriptHashAddrID, script.Hash160(m.RedeemScript))
+}
@@ -0,0 +1,57 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+)
+
+const privateKeyLength = 32
+
+// EncodePrivateKey exports a private key as network version + key type + key +
+// checksum in base58, so that typos are caught on import
+func EncodePrivateKey(privateKey PrivateKey) string {
+	versionedKey := append([]byte{chaincfg.Active.PrivateKeyID, byte(privateKey.Type)}, privateKey.Key...)
+	fullKey := append(versionedKey, Checksum(versionedKey)...)
+
+	return string(EncodeBase58(fullKey))
+}
+
+// DecodePrivateKey parses a key exported by EncodePrivateKey. Keys exported
+// before key types existed carry no type and are P-256 keys
+func DecodePrivateKey(encoded string) (PrivateKey, error) {
+	versionedKey, err := DecodeCheck(encoded)
+	if err != nil {
+		return PrivateKey{}, fmt.Errorf("invalid private key: %s", err)
+	}
+	if len(versionedKey) != 1+privateKeyLength && len(versionedKey) != 2+privateKeyLength {
+		return PrivateKey{}, errors.New("private key has an invalid length")
+	}
+	if versionedKey[0] != chaincfg.Active.PrivateKeyID {
+		return PrivateKey{}, errors.New("not a private key of this network")
+	}
+
+	keyType, key := KeyP256, versionedKey[1:]
+	if len(key) > privateKeyLength {
+		keyType, key = KeyType(key[0]), key[1:]
+	}
+
+	return NewPrivateKey(keyType, key)
+}
+
+// ImportWallet stores the wallet of an existing private key and returns its
+// address
+func (ws Wallets) ImportWallet(privateKey PrivateKey) string {
+	wallet := Wallet{privateKey, privateKey.PublicKey(), ""}
+	address := string(wallet.Address())
+
+	// Keep the derivation path of keys we already know
+	if _, ok := ws.Wallets[address]; !ok {
+		ws.Wallets[address] = &wallet
+	}
+	// The address becomes spendable
+	delete(ws.WatchOnly, address)
+
+	return address
+}
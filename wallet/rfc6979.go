@@ -0,0 +1,63 @@
+package wallet
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// SigningEntropy is mixed into ECDSA nonces as the additional data of
+// RFC 6979 section 3.6 when set, P-256 nonces are drawn at random instead.
+// Signatures are reproducible only while it is nil, the default
+var SigningEntropy io.Reader
+
+// signingExtra reads the additional nonce data from SigningEntropy, nil
+// when it is not set
+func signingExtra() ([]byte, error) {
+	if SigningEntropy == nil {
+		return nil, nil
+	}
+
+	extra := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(SigningEntropy, extra); err != nil {
+		return nil, err
+	}
+	return extra, nil
+}
+
+// signECDSA signs hash with a P-256 key and returns r and s. crypto/ecdsa
+// derives the nonce of RFC 6979 and keeps the arithmetic on the key and the
+// nonce constant time, a random nonce is used when extra is set
+func (k PrivateKey) signECDSA(hash, extra []byte) (*big.Int, *big.Int, error) {
+	if len(hash) != sha256.Size {
+		return nil, nil, errors.New("hash to sign is not a sha256 hash")
+	}
+
+	private, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), k.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var random io.Reader
+	if extra != nil {
+		random = rand.Reader
+	}
+
+	der, err := private.Sign(random, hash, crypto.SHA256)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var signature struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &signature); err != nil {
+		return nil, nil, err
+	}
+
+	return signature.R, signature.S, nil
+}
@@ -0,0 +1,96 @@
+package wallet
+
+import (
+	"crypto/elliptic"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// S256 returns the secp256k1 curve y² = x³ + 7 used by Bitcoin signers. Only
+// its parameters are used here, keys, signatures and points are handled by
+// the dcrd implementation on fixed size field elements
+func S256() elliptic.Curve {
+	return secp256k1.S256()
+}
+
+// secp256k1PublicKey returns the SEC1 compressed public key of a private scalar
+func secp256k1PublicKey(key []byte) []byte {
+	private := secp256k1.PrivKeyFromBytes(key)
+	defer private.Zero()
+
+	return private.PubKey().SerializeCompressed()
+}
+
+// signSecp256k1 signs hash with the nonce of RFC 6979, extra is its optional
+// additional data. It returns r || s with s in the lower half of the order
+func signSecp256k1(key, hash, extra []byte) []byte {
+	private := secp256k1.PrivKeyFromBytes(key)
+	defer private.Zero()
+
+	var e secp256k1.ModNScalar
+	e.SetByteSlice(hash)
+
+	for iteration := uint32(0); ; iteration++ {
+		nonce := secp256k1.NonceRFC6979(key, hash, extra, nil, iteration)
+
+		var point secp256k1.JacobianPoint
+		secp256k1.ScalarBaseMultNonConst(nonce, &point)
+		point.ToAffine()
+
+		// r = x mod n
+		var r secp256k1.ModNScalar
+		r.SetBytes(point.X.Bytes())
+		if r.IsZero() {
+			nonce.Zero()
+			continue
+		}
+
+		// s = nonce⁻¹ (e + r d) mod n
+		nonceInv := new(secp256k1.ModNScalar).InverseValNonConst(nonce)
+		nonce.Zero()
+		s := new(secp256k1.ModNScalar).Mul2(&private.Key, &r).Add(&e).Mul(nonceInv)
+		if s.IsZero() {
+			continue
+		}
+		if s.IsOverHalfOrder() {
+			s.Negate()
+		}
+
+		rs := make([]byte, 2*scalarLength)
+		r.PutBytesUnchecked(rs[:scalarLength])
+		s.PutBytesUnchecked(rs[scalarLength:])
+		return rs
+	}
+}
+
+// parseSecp256k1PublicKey parses a SEC1 point, compressed or not, nil when
+// it is not on the curve
+func parseSecp256k1PublicKey(key []byte) *secp256k1.PublicKey {
+	switch {
+	case len(key) == 1+scalarLength && (key[0] == 0x02 || key[0] == 0x03):
+	case len(key) == 1+2*scalarLength && key[0] == 0x04:
+	default:
+		return nil
+	}
+
+	public, err := secp256k1.ParsePubKey(key)
+	if err != nil {
+		return nil
+	}
+	return public
+}
+
+// verifySecp256k1 checks the r || s signature of hash, s must be in the lower
+// half of the order
+func verifySecp256k1(public *secp256k1.PublicKey, hash, rs []byte) bool {
+	var r, s secp256k1.ModNScalar
+	if r.SetByteSlice(rs[:scalarLength]) || s.SetByteSlice(rs[scalarLength:]) {
+		return false
+	}
+	if r.IsZero() || s.IsZero() || s.IsOverHalfOrder() {
+		return false
+	}
+
+	return ecdsa.NewSignature(&r, &s).Verify(hash, public)
+}
@@ -0,0 +1,156 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// walletSessionSocket is where the running node hands out the key of an
+// unlocked wallet to the commands of the same node. Only the owner of the
+// data directory can connect to it
+const walletSessionSocket = "wallets_%s.sock"
+
+// ErrNoNode is returned by Unlock when no node is running to keep the key
+var ErrNoNode = errors.New("no node is running to keep the wallet unlocked, start it with startnode")
+
+// sessionRequest is sent by wallet commands to the node, Command is one of
+// unlock, lock or key
+type sessionRequest struct {
+	Command string
+	Salt    []byte
+	Key     []byte
+	Timeout time.Duration
+}
+
+type sessionResponse struct {
+	Key   []byte
+	Error string
+}
+
+// session is the key of the unlocked wallet, it only lives in the memory of
+// the node and is wiped when its timer fires
+type session struct {
+	mutex sync.Mutex
+	salt  []byte
+	key   []byte
+	timer *time.Timer
+}
+
+func (s *session) unlock(salt, key []byte, timeout time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.clear()
+	s.salt, s.key = salt, key
+	s.timer = time.AfterFunc(timeout, s.lock)
+}
+
+func (s *session) lock() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.clear()
+}
+
+// clear wipes the key, the caller holds the mutex
+func (s *session) clear() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	for i := range s.key {
+		s.key[i] = 0
+	}
+	s.salt, s.key = nil, nil
+}
+
+func (s *session) lookup(salt []byte) []byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.key == nil || !bytes.Equal(s.salt, salt) {
+		return nil
+	}
+	return append([]byte{}, s.key...)
+}
+
+// ServeSessions keeps the unlocked wallet of the node in memory and answers
+// the wallet commands of the node, it runs as long as the node
+func ServeSessions(nodeID string) error {
+	socketPath := dataFile(walletSessionSocket, nodeID)
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0700); err != nil {
+		return err
+	}
+	os.Remove(socketPath)
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		return err
+	}
+
+	var unlocked session
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go handleSession(conn, &unlocked)
+	}
+}
+
+func handleSession(conn net.Conn, unlocked *session) {
+	defer conn.Close()
+
+	var request sessionRequest
+	if err := gob.NewDecoder(conn).Decode(&request); err != nil {
+		return
+	}
+
+	var response sessionResponse
+	switch request.Command {
+	case "unlock":
+		unlocked.unlock(request.Salt, request.Key, request.Timeout)
+	case "lock":
+		unlocked.lock()
+	case "key":
+		response.Key = unlocked.lookup(request.Salt)
+	default:
+		response.Error = fmt.Sprintf("unknown session command %q", request.Command)
+	}
+
+	gob.NewEncoder(conn).Encode(response)
+}
+
+// askSession sends a request to the node, ErrNoNode when none is running
+func askSession(nodeID string, request sessionRequest) (sessionResponse, error) {
+	var response sessionResponse
+
+	conn, err := net.Dial("unix", dataFile(walletSessionSocket, nodeID))
+	if err != nil {
+		return response, ErrNoNode
+	}
+	defer conn.Close()
+
+	if err := gob.NewEncoder(conn).Encode(request); err != nil {
+		return response, err
+	}
+	if err := gob.NewDecoder(conn).Decode(&response); err != nil {
+		return response, err
+	}
+	if response.Error != "" {
+		return response, errors.New(response.Error)
+	}
+	return response, nil
+}
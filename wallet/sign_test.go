@@ -0,0 +1,118 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestSignRFC6979 checks the P-256 SHA-256 signature of "sample" from
+// RFC 6979 appendix A.2.5
+func TestSignRFC6979(t *testing.T) {
+	key := PrivateKey{KeyP256, mustDecodeHex(t, "c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721")}
+	hash := sha256.Sum256([]byte("sample"))
+
+	wantR := "efd48b2aacb6a8fd1140dd9cd45e81d69d2c877b56aaf991c34d0ea84eaf3716"
+	wantS := "f7cb1c942d657c41d436c7a1b6e29f65f3e900dbb9aff4064dc4ab2f843acda8"
+
+	r, s, err := key.signECDSA(hash[:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(r.Bytes()) != wantR || hex.EncodeToString(s.Bytes()) != wantS {
+		t.Errorf("signECDSA = %x %x, want %s %s", r, s, wantR, wantS)
+	}
+
+	// Sign keeps s in the lower half of the order
+	signature, err := key.Sign(hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	lowS := new(big.Int).SetBytes(mustDecodeHex(t, wantS))
+	lowS.Sub(KeyP256.curve().Params().N, lowS)
+
+	want := "00" + wantR + hex.EncodeToString(lowS.FillBytes(make([]byte, scalarLength)))
+	if hex.EncodeToString(signature) != want {
+		t.Errorf("Sign = %x, want %s", signature, want)
+	}
+	if !VerifySignature(key.PublicKey(), hash[:], signature) {
+		t.Error("VerifySignature rejected the signature")
+	}
+}
+
+// TestSignSecp256k1 checks the widely used secp256k1 vector of the private
+// key 1 signing "Satoshi Nakamoto"
+func TestSignSecp256k1(t *testing.T) {
+	key := PrivateKey{KeySecp256k1, mustDecodeHex(t, "0000000000000000000000000000000000000000000000000000000000000001")}
+	hash := sha256.Sum256([]byte("Satoshi Nakamoto"))
+
+	want := "01" +
+		"934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8" +
+		"2442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5"
+
+	signature, err := key.Sign(hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(signature) != want {
+		t.Errorf("Sign = %x, want %s", signature, want)
+	}
+
+	wantPublicKey := "01" + "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	if hex.EncodeToString(key.PublicKey()) != wantPublicKey {
+		t.Errorf("PublicKey = %x, want %s", key.PublicKey(), wantPublicKey)
+	}
+
+	if !VerifySignature(key.PublicKey(), hash[:], signature) {
+		t.Error("VerifySignature rejected the signature")
+	}
+	signature[len(signature)-1] ^= 1
+	if VerifySignature(key.PublicKey(), hash[:], signature) {
+		t.Error("VerifySignature accepted an altered signature")
+	}
+}
+
+// TestSignEntropy checks that signatures made with SigningEntropy set still
+// verify, and differ from the deterministic ones
+func TestSignEntropy(t *testing.T) {
+	hash := sha256.Sum256([]byte("sample"))
+
+	for _, keyType := range []KeyType{KeyP256, KeySecp256k1} {
+		key, err := GenerateKey(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		deterministic, err := key.Sign(hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		SigningEntropy = rand.Reader
+		hedged, err := key.Sign(hash[:])
+		SigningEntropy = nil
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if bytes.Equal(deterministic, hedged) {
+			t.Errorf("%s: signature with entropy is the deterministic one", keyType)
+		}
+		if !VerifySignature(key.PublicKey(), hash[:], hedged) || !CheckSignatureEncoding(key.PublicKey(), hedged) {
+			t.Errorf("%s: signature with entropy rejected", keyType)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package wallet_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"gitlab.com/thesepehrm/first-blockchain/blockchain"
+	"gitlab.com/thesepehrm/first-blockchain/script"
+	"gitlab.com/thesepehrm/first-blockchain/wallet"
+)
+
+// TestSignTransaction pins the signature and id of a transaction signed with
+// a fixed key, so that changes to the signature hash or the key encoding show
+func TestSignTransaction(t *testing.T) {
+	key, err := hex.DecodeString("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721")
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey, err := wallet.NewPrivateKey(wallet.KeyP256, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lockingScript := script.PayToPubKeyHash(wallet.PublicKeyHash(privateKey.PublicKey()))
+
+	prevTx := blockchain.Transaction{
+		Inputs:  []blockchain.TxInput{{ID: []byte{}, Out: -1, UnlockingScript: []byte("fixed"), Sequence: blockchain.MaxSequence}},
+		Outputs: []blockchain.TxOutput{{Value: 50, LockingScript: lockingScript}},
+	}
+	prevTx.ID = prevTx.Hash()
+
+	tx := blockchain.Transaction{
+		Inputs: []blockchain.TxInput{{ID: prevTx.ID, Out: 0, Sequence: blockchain.MaxSequence}},
+		Outputs: []blockchain.TxOutput{
+			{Value: 30, LockingScript: script.PayToPubKeyHash(make([]byte, 20))},
+			{Value: 19, LockingScript: lockingScript},
+		},
+	}
+	tx.ID = tx.Hash()
+
+	prevTxs := map[string]blockchain.Transaction{hex.EncodeToString(prevTx.ID): prevTx}
+	tx.Sign(privateKey, prevTxs, blockchain.SigHashAll)
+
+	wantID := "3bb70806727019e223a876d6c625ba4220e21b2332289552deb6b40b7ecfe073"
+	wantUnlockingScript := "4200a60410994df970cb6021d69f1c5cddea0fecdac92f79bf58b85d42096c7f17205692a9587f93ada24191ceb3f222b8f582be0c0386fbf2ee19ba791037c7b56c0122000360fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6"
+
+	if hex.EncodeToString(tx.ID) != wantID {
+		t.Errorf("id = %x, want %s", tx.ID, wantID)
+	}
+	if hex.EncodeToString(tx.Inputs[0].UnlockingScript) != wantUnlockingScript {
+		t.Errorf("unlocking script = %x, want %s", tx.Inputs[0].UnlockingScript, wantUnlockingScript)
+	}
+
+	// Signing does not change the id
+	if hex.EncodeToString(tx.Hash()) != wantID {
+		t.Errorf("id after signing = %x, want %s", tx.Hash(), wantID)
+	}
+	if !tx.Verify(prevTxs) {
+		t.Error("Verify rejected the signed transaction")
+	}
+}
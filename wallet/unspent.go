@@ -0,0 +1,200 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// OutputState tells whether an output of the wallet may be spent
+type OutputState int
+
+const (
+	// OutputConfirmed outputs are mined and free to spend
+	OutputConfirmed OutputState = iota
+	// OutputPendingSpend outputs are spent by a transaction of the wallet
+	// that is not mined yet
+	OutputPendingSpend
+	// OutputLocked outputs are kept out of coin selection by the user
+	OutputLocked
+)
+
+func (state OutputState) String() string {
+	switch state {
+	case OutputPendingSpend:
+		return "pending-spend"
+	case OutputLocked:
+		return "locked"
+	}
+	return "confirmed"
+}
+
+// OutputRecord is an unspent output owned or watched by the wallet
+type OutputRecord struct {
+	TxID          []byte
+	Index         int
+	Value         int
+	LockingScript []byte
+	Address       string
+	Height        int
+	State         OutputState
+	SpentBy       []byte // Pending transaction spending the output
+	SpentAt       int64  // When the pending transaction was sent
+}
+
+// Outpoint identifies the output as TXID:INDEX
+func (rec *OutputRecord) Outpoint() string {
+	return Outpoint(rec.TxID, rec.Index)
+}
+
+func Outpoint(txID []byte, index int) string {
+	return fmt.Sprintf("%x:%d", txID, index)
+}
+
+// AddUnspent records an output paying to the wallet, keeping the state of
+// an output seen before a rescan
+func (ws *Wallets) AddUnspent(rec OutputRecord) {
+	if ws.Unspent == nil {
+		ws.Unspent = make(map[string]*OutputRecord)
+	}
+
+	if known, ok := ws.forgotten[rec.Outpoint()]; ok {
+		rec.State, rec.SpentBy, rec.SpentAt = known.State, known.SpentBy, known.SpentAt
+	}
+	ws.Unspent[rec.Outpoint()] = &rec
+}
+
+// SpendOutput forgets an output once an input of a mined transaction
+// spends it. A pending transaction of the wallet spending it as well can not
+// be mined anymore and is dropped
+func (ws *Wallets) SpendOutput(txID []byte, index int, spender []byte) bool {
+	outpoint := Outpoint(txID, index)
+	rec, ok := ws.Unspent[outpoint]
+	if !ok {
+		return false
+	}
+	delete(ws.Unspent, outpoint)
+
+	if rec.State == OutputPendingSpend && !bytes.Equal(rec.SpentBy, spender) {
+		ws.DropPending(rec.SpentBy)
+	}
+	return true
+}
+
+// MarkPending reserves the outputs spent by a transaction the wallet sent
+// until it is mined or dropped, outputs the wallet did not know are added.
+// Other processes only see the reservation once the wallet is saved, hold
+// LockWallets from loading the wallet until then
+func (ws *Wallets) MarkPending(spent []OutputRecord, spender []byte, sentAt int64) {
+	for _, rec := range spent {
+		if _, ok := ws.Unspent[rec.Outpoint()]; !ok {
+			ws.AddUnspent(rec)
+		}
+
+		known := ws.Unspent[rec.Outpoint()]
+		known.State = OutputPendingSpend
+		known.SpentBy = spender
+		known.SpentAt = sentAt
+	}
+}
+
+// DropPending releases the outputs reserved by a pending transaction that
+// will not be mined, and flags it in the history
+func (ws *Wallets) DropPending(spender []byte) {
+	for _, rec := range ws.Unspent {
+		if rec.State == OutputPendingSpend && bytes.Equal(rec.SpentBy, spender) {
+			rec.State = OutputConfirmed
+			rec.SpentBy = nil
+			rec.SpentAt = 0
+		}
+	}
+
+	if tx, ok := ws.Transactions[hex.EncodeToString(spender)]; ok && tx.Height < 0 {
+		tx.Dropped = true
+	}
+}
+
+// ReleaseExpired drops the pending transactions sent longer than expiry ago,
+// nodes forget unmined transactions after a while
+func (ws *Wallets) ReleaseExpired(now time.Time, expiry time.Duration) int {
+	expired := make(map[string][]byte)
+	for _, rec := range ws.Unspent {
+		if rec.State == OutputPendingSpend && now.Sub(time.Unix(rec.SpentAt, 0)) > expiry {
+			expired[hex.EncodeToString(rec.SpentBy)] = rec.SpentBy
+		}
+	}
+
+	for _, spender := range expired {
+		ws.DropPending(spender)
+	}
+	return len(expired)
+}
+
+// LockUnspent keeps an output out of coin selection, or gives it back
+func (ws *Wallets) LockUnspent(outpoint string, lock bool) error {
+	rec, ok := ws.Unspent[outpoint]
+	if !ok {
+		return fmt.Errorf("output %s is not an unspent output of the wallet", outpoint)
+	}
+
+	switch {
+	case rec.State == OutputPendingSpend:
+		return fmt.Errorf("output %s is spent by pending transaction %x", outpoint, rec.SpentBy)
+	case lock:
+		rec.State = OutputLocked
+	default:
+		rec.State = OutputConfirmed
+	}
+	return nil
+}
+
+// ReservedOutpoints returns the outputs coin selection has to skip
+func (ws *Wallets) ReservedOutpoints() map[string]bool {
+	reserved := make(map[string]bool)
+	for outpoint, rec := range ws.Unspent {
+		if rec.State != OutputConfirmed {
+			reserved[outpoint] = true
+		}
+	}
+	return reserved
+}
+
+// ListUnspent returns the unspent outputs of the wallet, oldest first
+func (ws *Wallets) ListUnspent() []*OutputRecord {
+	var records []*OutputRecord
+	for _, rec := range ws.Unspent {
+		records = append(records, rec)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Height != records[j].Height {
+			return records[i].Height < records[j].Height
+		}
+		if c := bytes.Compare(records[i].TxID, records[j].TxID); c != 0 {
+			return c < 0
+		}
+		return records[i].Index < records[j].Index
+	})
+
+	return records
+}
+
+// ForgetFrom drops what the wallet learnt from the blocks at or above height,
+// so that they can be scanned again. Their transactions are kept, with their
+// memos, as unconfirmed until they are seen again
+func (ws *Wallets) ForgetFrom(height int) {
+	for _, rec := range ws.Transactions {
+		if rec.Height >= height {
+			rec.Height = -1
+		}
+	}
+	ws.forgotten = make(map[string]*OutputRecord)
+	for outpoint, rec := range ws.Unspent {
+		if rec.Height >= height {
+			ws.forgotten[outpoint] = rec
+			delete(ws.Unspent, outpoint)
+		}
+	}
+}
@@ -2,38 +2,35 @@ package wallet
 
 import (
 	"bytes"
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/sha256"
+	"errors"
+	"fmt"
 
+	"github.com/mr-tron/base58"
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
 	"golang.org/x/crypto/ripemd160"
 )
 
-const (
-	checksumLength = 4
-	version        = byte(0x00)
-)
+const checksumLength = 4
 
 type Wallet struct {
-	PrivateKey ecdsa.PrivateKey
+	PrivateKey PrivateKey
 	PublicKey  []byte
+	Path       string // Derivation path when the key comes from a seed phrase
 }
 
 //NewKeyPair can generate up to 10^77 different keys which is just 1/10 number of atoms in the universe
-func NewKeyPair() (ecdsa.PrivateKey, []byte) {
-	curve := elliptic.P256()
-	private, err := ecdsa.GenerateKey(curve, rand.Reader)
+func NewKeyPair(keyType KeyType) (PrivateKey, []byte) {
+	private, err := GenerateKey(keyType)
 	Handle(err)
 
-	pub := append(private.PublicKey.X.Bytes(), private.PublicKey.Y.Bytes()...)
-	return *private, pub
+	return private, private.PublicKey()
 
 }
 
-func MakeWallet() *Wallet {
-	privateKey, publicKey := NewKeyPair()
-	wallet := Wallet{privateKey, publicKey}
+func MakeWallet(keyType KeyType) *Wallet {
+	privateKey, publicKey := NewKeyPair(keyType)
+	wallet := Wallet{privateKey, publicKey, ""}
 	return &wallet
 }
 
@@ -58,26 +55,64 @@ func Checksum(keyHash []byte) []byte {
 }
 
 func (w Wallet) Address() []byte {
-	publicKeyHash := PublicKeyHash(w.PublicKey)
+	return PublicKeyAddress(w.PublicKey)
+}
 
-	versionedPublicKeyHash := append([]byte{version}, publicKeyHash...)
-	checksum := Checksum(versionedPublicKeyHash)
+// PublicKeyAddress : tagged public key -> address with the version of its key
+// type
+func PublicKeyAddress(publicKey []byte) []byte {
+	publicKeyHash := PublicKeyHash(publicKey)
+
+	return EncodeAddress(KeyType(publicKey[0]).AddressVersion(), publicKeyHash)
+}
 
-	fullHash := append(versionedPublicKeyHash, checksum...)
+// EncodeAddress : version + hash + checksum -> base58
+func EncodeAddress(version byte, hash []byte) []byte {
+	versionedHash := append([]byte{version}, hash...)
+	checksum := Checksum(versionedHash)
 
-	address := EncodeBase58(fullHash)
+	fullHash := append(versionedHash, checksum...)
 
-	return address
+	return EncodeBase58(fullHash)
 }
 
-func ValidateAddress(address string) bool {
-	decodedAddress := DecodeBase58([]byte(address))
+// DecodeCheck decodes base58 text ending with the checksum of its content and
+// returns the content
+func DecodeCheck(encoded string) ([]byte, error) {
+	decoded, err := base58.Decode(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) <= checksumLength {
+		return nil, errors.New("encoded data is too short")
+	}
+
+	content := decoded[:len(decoded)-checksumLength]
+	if !bytes.Equal(Checksum(content), decoded[len(decoded)-checksumLength:]) {
+		return nil, errors.New("checksum mismatch")
+	}
+
+	return content, nil
+}
 
-	version := decodedAddress[0]
-	inputChecksum := decodedAddress[len(decodedAddress)-checksumLength:]
-	keyHash := decodedAddress[1 : len(decodedAddress)-checksumLength]
+// DecodeAddress returns the version and the public key or script hash of an
+// address
+func DecodeAddress(address string) (byte, []byte, error) {
+	versionedHash, err := DecodeCheck(address)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid address %s: %s", address, err)
+	}
+	if len(versionedHash) != 1+ripemd160.Size {
+		return 0, nil, fmt.Errorf("invalid address %s: wrong length", address)
+	}
+
+	return versionedHash[0], versionedHash[1:], nil
+}
 
-	checksum := Checksum(append([]byte{version}, keyHash...))
+// ValidateAddress reports whether address is an address of the active
+// network with a valid checksum
+func ValidateAddress(address string) bool {
+	version, _, err := DecodeAddress(address)
 
-	return bytes.Compare(checksum, inputChecksum) == 0
+	return err == nil && chaincfg.Active.IsAddressID(version)
 }
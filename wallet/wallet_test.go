@@ -0,0 +1,57 @@
+package wallet
+
+import (
+	"bytes"
+	"testing"
+
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+)
+
+func TestDecodeAddress(t *testing.T) {
+	hash := bytes.Repeat([]byte{0xab}, 20)
+	address := string(EncodeAddress(chaincfg.Active.PubKeyHashAddrID, hash))
+
+	version, decoded, err := DecodeAddress(address)
+	if err != nil {
+		t.Fatalf("DecodeAddress(%s): %s", address, err)
+	}
+	if version != chaincfg.Active.PubKeyHashAddrID || !bytes.Equal(decoded, hash) {
+		t.Errorf("DecodeAddress(%s) = %x %x, want %x %x", address, version, decoded, chaincfg.Active.PubKeyHashAddrID, hash)
+	}
+
+	invalid := []string{
+		"",
+		"1",
+		"0OIl",
+		string(EncodeBase58([]byte{1, 2, 3})),
+		string(EncodeAddress(chaincfg.Active.PubKeyHashAddrID, hash[:19])),
+		address[:len(address)-1] + "z",
+	}
+	for _, address := range invalid {
+		if _, _, err := DecodeAddress(address); err == nil {
+			t.Errorf("DecodeAddress(%q) accepted an invalid address", address)
+		}
+		if ValidateAddress(address) {
+			t.Errorf("ValidateAddress(%q) = true", address)
+		}
+	}
+}
+
+func TestDecodePrivateKey(t *testing.T) {
+	privateKey, _ := NewKeyPair(KeyP256)
+	encoded := EncodePrivateKey(privateKey)
+
+	decoded, err := DecodePrivateKey(encoded)
+	if err != nil {
+		t.Fatalf("DecodePrivateKey: %s", err)
+	}
+	if !bytes.Equal(decoded.Key, privateKey.Key) {
+		t.Errorf("DecodePrivateKey = %x, want %x", decoded.Key, privateKey.Key)
+	}
+
+	for _, encoded := range []string{"", "1", "0OIl", string(EncodeBase58([]byte{chaincfg.Active.PrivateKeyID}))} {
+		if _, err := DecodePrivateKey(encoded); err == nil {
+			t.Errorf("DecodePrivateKey(%q) accepted an invalid key", encoded)
+		}
+	}
+}
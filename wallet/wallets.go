@@ -2,37 +2,67 @@ package wallet
 
 import (
 	"bytes"
-	"crypto/elliptic"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
+	"path/filepath"
+
+	"gitlab.com/thesepehrm/first-blockchain/chaincfg"
+)
+
+const (
+	walletDBFile       = "wallets_%s.data"
+	legacyBackupSuffix = ".legacy"
 )
 
-const walletDBFile = "./tmp/wallets_%s.data"
+// dataFile is the path of a file of the node in the directory of the active
+// network
+func dataFile(format, nodeID string) string {
+	return filepath.Join(chaincfg.Active.DataDir, fmt.Sprintf(format, nodeID))
+}
 
 type Wallets struct {
-	Wallets map[string]*Wallet
+	Wallets       map[string]*Wallet
+	MultiSigs     map[string]*MultiSig
+	WatchOnly     map[string]*WatchOnly
+	CoinSelection string   // Preferred coin selection strategy, the default one when empty
+	HD            *HDChain // Seed the keys are derived from, nil for random keys
+
+	Transactions map[string]*TxRecord     // History by hex transaction id
+	Labels       map[string]string        // Address -> label
+	Unspent      map[string]*OutputRecord // Outputs of the wallet by outpoint
+	SyncedHeight int                      // Height of the first block the history has not seen
+
+	key       *walletKey               // Set when the file is encrypted with a passphrase
+	forgotten map[string]*OutputRecord // Outputs dropped by ForgetFrom until they are seen again
 }
 
 func (ws *Wallets) SaveFile(nodeID string) {
 	var content bytes.Buffer
 
-	walletPath := fmt.Sprintf(walletDBFile, nodeID)
+	walletPath := dataFile(walletDBFile, nodeID)
 
-	gob.Register(elliptic.P256())
 	encoder := gob.NewEncoder(&content)
 	err := encoder.Encode(ws)
 	Handle(err)
 
-	err = ioutil.WriteFile(walletPath, content.Bytes(), 0644)
+	fileContent := content.Bytes()
+	if ws.IsEncrypted() {
+		fileContent, err = ws.seal(fileContent)
+		Handle(err)
+	}
+
+	err = writePrivateFile(walletPath, fileContent)
 	Handle(err)
 
 }
 
 func (ws *Wallets) LoadFile(nodeID string) error {
 
-	walletPath := fmt.Sprintf(walletDBFile, nodeID)
+	walletPath := dataFile(walletDBFile, nodeID)
 
 	if _, err := os.Stat(walletPath); os.IsNotExist(err) {
 		return err
@@ -42,14 +72,57 @@ func (ws *Wallets) LoadFile(nodeID string) error {
 	var wallets Wallets
 
 	fileContent, err := ioutil.ReadFile(walletPath)
-	Handle(err)
+	if err != nil {
+		return err
+	}
+
+	stored := fileContent
+	if bytes.HasPrefix(fileContent, encryptedMagic) {
+		fileContent, err = ws.open(fileContent, nodeID)
+		if err != nil {
+			return err
+		}
+	}
 
-	gob.Register(elliptic.P256())
 	decoder := gob.NewDecoder(bytes.NewReader(fileContent))
 	err = decoder.Decode(&wallets)
-	Handle(err)
+	if err == nil && wallets.hasUntypedKeys() {
+		err = errors.New("keys of an unknown format")
+	}
+	if err != nil {
+		if ws.loadLegacy(fileContent) != nil {
+			return fmt.Errorf("wallet file %s is corrupt: %s", walletPath, err)
+		}
+
+		// The legacy file is kept aside in case the upgrade is not wanted
+		if err := writePrivateFile(walletPath+legacyBackupSuffix, stored); err != nil {
+			return err
+		}
+		ws.SaveFile(nodeID)
+		log.Printf("Wallet file %s upgraded, the legacy file is kept as %s%s", walletPath, walletPath, legacyBackupSuffix)
+
+		return nil
+	}
 
 	ws.Wallets = wallets.Wallets
+	if wallets.MultiSigs != nil {
+		ws.MultiSigs = wallets.MultiSigs
+	}
+	if wallets.WatchOnly != nil {
+		ws.WatchOnly = wallets.WatchOnly
+	}
+	ws.CoinSelection = wallets.CoinSelection
+	ws.HD = wallets.HD
+	if wallets.Transactions != nil {
+		ws.Transactions = wallets.Transactions
+	}
+	if wallets.Labels != nil {
+		ws.Labels = wallets.Labels
+	}
+	if wallets.Unspent != nil {
+		ws.Unspent = wallets.Unspent
+	}
+	ws.SyncedHeight = wallets.SyncedHeight
 
 	return nil
 }
@@ -68,8 +141,8 @@ func (ws *Wallets) GetAllAddresses() []string {
 	return addresses
 }
 
-func (ws Wallets) AddWallet() string {
-	wallet := MakeWallet()
+func (ws Wallets) AddWallet(keyType KeyType) string {
+	wallet := MakeWallet(keyType)
 	address := fmt.Sprintf("%s", wallet.Address())
 
 	ws.Wallets[address] = wallet
@@ -77,9 +150,49 @@ func (ws Wallets) AddWallet() string {
 	return address
 }
 
+// AddMultiSig stores a multisig account so that its address can be watched
+// and spent from, and returns the address
+func (ws Wallets) AddMultiSig(multiSig *MultiSig) string {
+	address := string(multiSig.Address())
+
+	ws.MultiSigs[address] = multiSig
+
+	return address
+}
+
+func (ws Wallets) GetMultiSig(address string) (*MultiSig, bool) {
+	multiSig, ok := ws.MultiSigs[address]
+	return multiSig, ok
+}
+
+// FindByPublicKey returns the wallet owning the given public key
+func (ws Wallets) FindByPublicKey(publicKey []byte) (*Wallet, bool) {
+	for _, w := range ws.Wallets {
+		if bytes.Equal(w.PublicKey, publicKey) {
+			return w, true
+		}
+	}
+	return nil, false
+}
+
+// FindByPublicKeyHash returns the wallet whose public key hashes to pubKeyHash
+func (ws Wallets) FindByPublicKeyHash(pubKeyHash []byte) (*Wallet, bool) {
+	for _, w := range ws.Wallets {
+		if bytes.Equal(PublicKeyHash(w.PublicKey), pubKeyHash) {
+			return w, true
+		}
+	}
+	return nil, false
+}
+
 func CreateWallets(nodeID string) (*Wallets, error) {
 	wallets := Wallets{}
 	wallets.Wallets = make(map[string]*Wallet)
+	wallets.MultiSigs = make(map[string]*MultiSig)
+	wallets.WatchOnly = make(map[string]*WatchOnly)
+	wallets.Transactions = make(map[string]*TxRecord)
+	wallets.Labels = make(map[string]string)
+	wallets.Unspent = make(map[string]*OutputRecord)
 
 	err := wallets.LoadFile(nodeID)
 	return &wallets, err
@@ -0,0 +1,77 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ErrWatchOnly is returned when a key is needed for an address the wallet
+// only watches
+var ErrWatchOnly = errors.New("address is watch-only, the wallet holds no private key for it")
+
+// WatchOnly is an address tracked by the wallet without its private key,
+// e.g. a cold storage or customer address
+type WatchOnly struct {
+	Address   string
+	PublicKey []byte // Known when imported from a public key
+}
+
+// AddWatchOnly starts watching an address
+func (ws Wallets) AddWatchOnly(address string) (string, error) {
+	if !ValidateAddress(address) {
+		return "", fmt.Errorf("address %s is not valid", address)
+	}
+	if _, ok := ws.Wallets[address]; ok {
+		return "", fmt.Errorf("address %s is already spendable by the wallet", address)
+	}
+
+	if _, ok := ws.WatchOnly[address]; !ok {
+		ws.WatchOnly[address] = &WatchOnly{Address: address}
+	}
+
+	return address, nil
+}
+
+// AddWatchOnlyPublicKey starts watching the address of a public key, the key
+// is kept to help signers of transactions spending from it
+func (ws Wallets) AddWatchOnlyPublicKey(publicKey []byte) (string, error) {
+	if !ValidatePublicKey(publicKey) {
+		return "", errors.New("public key is not valid")
+	}
+
+	address, err := ws.AddWatchOnly(string(PublicKeyAddress(publicKey)))
+	if err != nil {
+		return "", err
+	}
+	ws.WatchOnly[address].PublicKey = publicKey
+
+	return address, nil
+}
+
+func (ws Wallets) GetWatchOnly(address string) (*WatchOnly, bool) {
+	watchOnly, ok := ws.WatchOnly[address]
+	return watchOnly, ok
+}
+
+// FindWatchOnlyByPublicKeyHash returns the watched address of pubKeyHash
+func (ws Wallets) FindWatchOnlyByPublicKeyHash(pubKeyHash []byte) (*WatchOnly, bool) {
+	for _, watchOnly := range ws.WatchOnly {
+		_, hash, err := DecodeAddress(watchOnly.Address)
+		if err == nil && bytes.Equal(hash, pubKeyHash) {
+			return watchOnly, true
+		}
+	}
+	return nil, false
+}
+
+// GetWatchOnlyAddresses returns the addresses watched without their keys
+func (ws Wallets) GetWatchOnlyAddresses() []string {
+	var addresses []string
+
+	for address := range ws.WatchOnly {
+		addresses = append(addresses, address)
+	}
+
+	return addresses
+}